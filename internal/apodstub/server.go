@@ -0,0 +1,340 @@
+// Package apodstub provides an offline stand-in for the NASA APOD API that serves recorded entries from a fixtures directory.
+package apodstub
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+const (
+	// APIPath is the path the APOD API is served at, as on api.nasa.gov.
+	APIPath = "/planetary/apod"
+
+	// filesPath is the path the files of the fixtures directory are served at.
+	filesPath = "/files/"
+
+	dateLayout = "2006-01-02"
+
+	// maxCount is the largest count accepted by the APOD API.
+	maxCount = 100
+
+	invalidCombinationMsg = "Bad Request: invalid field combination passed. Allowed request fields for apod method are 'concept_tags', 'date', 'hd', 'count', 'start_date', 'end_date', 'thumbs'"
+	invalidDateMsg        = "time data does not match format '%Y-%m-%d'"
+)
+
+// Options controls the failures simulated by the stub server.
+type Options struct {
+	// RateLimit is the number of API requests allowed per RateWindow. Zero disables rate limiting.
+	RateLimit  int
+	RateWindow time.Duration
+	// FailEvery makes every n-th API request fail with FailStatus. Zero disables failures.
+	FailEvery  int
+	FailStatus int
+	// Latency delays every API response.
+	Latency time.Duration
+}
+
+// NewServer returns a handler serving the APOD API at APIPath from the entries recorded in dir.
+//
+// Every entry is a YYYY-MM-DD.json file in the format of the APOD API; the date is taken from the file name.
+// Relative URLs of an entry refer to files in dir and are rewritten to be served by the stub itself.
+// The date, start_date, end_date, count and thumbs query parameters behave as on api.nasa.gov,
+// except that the current day is the latest recorded entry.
+func NewServer(dir string, opts Options) http.Handler {
+	return newServer(dir, opts).routes()
+}
+
+func newServer(dir string, opts Options) *server {
+	if opts.FailStatus == 0 {
+		opts.FailStatus = http.StatusServiceUnavailable
+	}
+	if opts.RateWindow <= 0 {
+		opts.RateWindow = time.Hour
+	}
+
+	return &server{
+		dir:  dir,
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+type server struct {
+	dir  string
+	opts Options
+	now  func() time.Time
+
+	mu          sync.Mutex
+	requests    int
+	windowStart time.Time
+	windowCount int
+}
+
+// routes returns the handler serving the API and the fixture files.
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+APIPath, s.apod)
+	mux.Handle("GET "+filesPath, http.StripPrefix(filesPath, http.FileServerFS(os.DirFS(s.dir))))
+	return mux
+}
+
+// errorResponse is the body of the error responses of the APOD API.
+type errorResponse struct {
+	Code           int    `json:"code"`
+	Msg            string `json:"msg"`
+	ServiceVersion string `json:"service_version"`
+}
+
+// gatewayError is the body of the error responses of the api.nasa.gov gateway.
+type gatewayError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// apod handles the requests of the APOD API.
+func (s *server) apod(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(s.opts.Latency):
+		}
+	}
+
+	query := r.URL.Query()
+	if query.Get("api_key") == "" {
+		writeGatewayError(w, http.StatusForbidden, "API_KEY_MISSING", "No api_key was supplied. Get one at https://api.nasa.gov:443")
+		return
+	}
+	if limited, retryAfter := s.limit(w); limited {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+		writeGatewayError(w, http.StatusTooManyRequests, "OVER_RATE_LIMIT", "You have exceeded your rate limit. Try again later.")
+		return
+	}
+	if s.fail() {
+		writeError(w, s.opts.FailStatus, "Simulated failure")
+		return
+	}
+
+	entries, err := s.load()
+	if err != nil {
+		log.Errorf("Error loading APOD fixtures: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Service Error")
+		return
+	}
+
+	baseURL := &url.URL{Scheme: "http", Host: r.Host, Path: filesPath}
+	if r.TLS != nil {
+		baseURL.Scheme = "https"
+	}
+	thumbs := query.Get("thumbs") == "true"
+
+	date, startDate, endDate, count := query.Get("date"), query.Get("start_date"), query.Get("end_date"), query.Get("count")
+	switch {
+	case count != "":
+		if date != "" || startDate != "" || endDate != "" {
+			writeError(w, http.StatusBadRequest, invalidCombinationMsg)
+			return
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 || n > maxCount {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Count must be positive and cannot exceed %d", maxCount))
+			return
+		}
+
+		result := make([]*model.APOD, 0, n)
+		for _, i := range rand.Perm(len(entries)) {
+			if len(result) == n {
+				break
+			}
+			result = append(result, render(entries[i], baseURL, thumbs))
+		}
+		writeJSON(w, result)
+	case startDate != "":
+		if date != "" {
+			writeError(w, http.StatusBadRequest, invalidCombinationMsg)
+			return
+		}
+		if endDate == "" && len(entries) > 0 {
+			endDate = entries[len(entries)-1].Date
+		}
+		if !validDate(startDate) || (endDate != "" && !validDate(endDate)) {
+			writeError(w, http.StatusBadRequest, invalidDateMsg)
+			return
+		}
+		if startDate > endDate {
+			writeError(w, http.StatusBadRequest, "start_date cannot be later than end_date")
+			return
+		}
+
+		result := []*model.APOD{}
+		for _, entry := range entries {
+			if entry.Date >= startDate && entry.Date <= endDate {
+				result = append(result, render(entry, baseURL, thumbs))
+			}
+		}
+		writeJSON(w, result)
+	case endDate != "":
+		writeError(w, http.StatusBadRequest, "Bad Request: end_date requires start_date")
+	default:
+		if date == "" && len(entries) > 0 {
+			date = entries[len(entries)-1].Date
+		}
+		if date != "" && !validDate(date) {
+			writeError(w, http.StatusBadRequest, invalidDateMsg)
+			return
+		}
+
+		for _, entry := range entries {
+			if entry.Date == date {
+				writeJSON(w, render(entry, baseURL, thumbs))
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "No data available for date: "+date)
+	}
+}
+
+// limit counts the request against the rate limit and reports whether it exceeds it,
+// along with the time until the limit resets.
+func (s *server) limit(w http.ResponseWriter) (bool, time.Duration) {
+	if s.opts.RateLimit <= 0 {
+		return false, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.windowStart) >= s.opts.RateWindow {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+
+	remaining := s.opts.RateLimit - s.windowCount
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.opts.RateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+	if s.windowCount <= s.opts.RateLimit {
+		return false, 0
+	}
+	return true, s.windowStart.Add(s.opts.RateWindow).Sub(now)
+}
+
+// fail counts the request and reports whether it is one of the requests that fail.
+func (s *server) fail() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	return s.opts.FailEvery > 0 && s.requests%s.opts.FailEvery == 0
+}
+
+// load reads the recorded entries in chronological order.
+func (s *server) load() ([]*model.APOD, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*model.APOD
+	for _, file := range files {
+		date, ok := strings.CutSuffix(file.Name(), ".json")
+		if !ok || file.IsDir() || !validDate(date) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var entry model.APOD
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file.Name(), err)
+		}
+		entry.Date = date
+		entries = append(entries, &entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Date < entries[j].Date
+	})
+	return entries, nil
+}
+
+// render returns a copy of the entry as served by the API.
+// Relative URLs are resolved against baseURL and the thumbnail is only included for videos if requested.
+func render(entry *model.APOD, baseURL *url.URL, thumbs bool) *model.APOD {
+	rendered := *entry
+	rendered.URL = resolve(baseURL, entry.URL)
+	rendered.HDURL = resolve(baseURL, entry.HDURL)
+	rendered.ThumbnailURL = ""
+	if thumbs && entry.MediaType == model.MediaTypeVideo {
+		rendered.ThumbnailURL = resolve(baseURL, entry.ThumbnailURL)
+	}
+	return &rendered
+}
+
+// resolve resolves a relative reference against baseURL and returns absolute URLs unchanged.
+func resolve(baseURL *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return baseURL.ResolveReference(u).String()
+}
+
+func validDate(date string) bool {
+	_, err := time.Parse(dateLayout, date)
+	return err == nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(errorResponse{Code: status, Msg: msg, ServiceVersion: "v1"}); err != nil {
+		log.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+func writeGatewayError(w http.ResponseWriter, status int, code, message string) {
+	var body gatewayError
+	body.Error.Code = code
+	body.Error.Message = message
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Errorf("Failed to encode response: %v", err)
+	}
+}
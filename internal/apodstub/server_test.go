@@ -0,0 +1,238 @@
+package apodstub
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(NewServer("testdata", opts))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, rawURL string) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(rawURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, body
+}
+
+func TestServer_APOD(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, Options{})
+	apiURL := srv.URL + APIPath + "?api_key=DEMO_KEY&"
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedDates      []string
+		check              func(t *testing.T, apods []*model.APOD)
+	}{
+		{
+			name:               "Latest",
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-20"},
+		},
+		{
+			name:               "Date",
+			query:              "date=2024-05-18",
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-18"},
+			check: func(t *testing.T, apods []*model.APOD) {
+				require.Equal(t, srv.URL+"/files/images/2024-05-18.png", apods[0].URL)
+				require.Equal(t, srv.URL+"/files/images/2024-05-18_hd.png", apods[0].HDURL)
+				require.Equal(t, "Jane Doe", apods[0].Copyright)
+			},
+		},
+		{
+			name:               "VideoWithoutThumbs",
+			query:              "date=2024-05-19",
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-19"},
+			check: func(t *testing.T, apods []*model.APOD) {
+				require.Equal(t, "https://www.youtube.com/embed/abcdef?rel=0", apods[0].URL)
+				require.Empty(t, apods[0].ThumbnailURL)
+			},
+		},
+		{
+			name:               "VideoWithThumbs",
+			query:              "date=2024-05-19&thumbs=true",
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-19"},
+			check: func(t *testing.T, apods []*model.APOD) {
+				require.Equal(t, srv.URL+"/files/images/2024-05-19_thumb.png", apods[0].ThumbnailURL)
+			},
+		},
+		{
+			name:               "Range",
+			query:              "start_date=2024-05-01&end_date=2024-05-19",
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-18", "2024-05-19"},
+		},
+		{
+			name:               "OpenRange",
+			query:              "start_date=2024-05-19",
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-19", "2024-05-20"},
+		},
+		{
+			name:               "Count",
+			query:              "count=2",
+			expectedStatusCode: http.StatusOK,
+			check: func(t *testing.T, apods []*model.APOD) {
+				require.Len(t, apods, 2)
+				require.NotEqual(t, apods[0].Date, apods[1].Date)
+			},
+		},
+		{
+			name:               "CountAboveRecorded",
+			query:              "count=10",
+			expectedStatusCode: http.StatusOK,
+			check: func(t *testing.T, apods []*model.APOD) {
+				require.Len(t, apods, 3)
+			},
+		},
+		{
+			name:               "NotFound",
+			query:              "date=2024-01-01",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "InvalidDate",
+			query:              "date=2024-5-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "StartAfterEnd",
+			query:              "start_date=2024-05-20&end_date=2024-05-18",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "EndWithoutStart",
+			query:              "end_date=2024-05-18",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "CountWithDate",
+			query:              "count=2&date=2024-05-18",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "InvalidCount",
+			query:              "count=101",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := get(t, apiURL+tt.query)
+			require.Equal(t, tt.expectedStatusCode, resp.StatusCode, string(body))
+			if tt.expectedStatusCode != http.StatusOK {
+				var errResp errorResponse
+				require.NoError(t, json.Unmarshal(body, &errResp))
+				require.Equal(t, tt.expectedStatusCode, errResp.Code)
+				return
+			}
+
+			var apods []*model.APOD
+			if body[0] == '[' {
+				require.NoError(t, json.Unmarshal(body, &apods))
+			} else {
+				var apod model.APOD
+				require.NoError(t, json.Unmarshal(body, &apod))
+				apods = append(apods, &apod)
+			}
+
+			if tt.expectedDates != nil {
+				dates := []string{}
+				for _, apod := range apods {
+					dates = append(dates, apod.Date)
+				}
+				require.Equal(t, tt.expectedDates, dates)
+			}
+			if tt.check != nil {
+				tt.check(t, apods)
+			}
+		})
+	}
+}
+
+func TestServer_Files(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, Options{})
+
+	resp, body := get(t, srv.URL+"/files/images/2024-05-18.png")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/png", http.DetectContentType(body))
+}
+
+func TestServer_MissingAPIKey(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, Options{})
+
+	resp, body := get(t, srv.URL+APIPath)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Contains(t, string(body), "API_KEY_MISSING")
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 18, 12, 0, 0, 0, time.UTC)
+	stub := newServer("testdata", Options{RateLimit: 2, RateWindow: time.Minute})
+	stub.now = func() time.Time { return now }
+
+	srv := httptest.NewServer(stub.routes())
+	t.Cleanup(srv.Close)
+
+	for i := 0; i < 2; i++ {
+		resp, _ := get(t, srv.URL+APIPath+"?api_key=DEMO_KEY")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "2", resp.Header.Get("X-RateLimit-Limit"))
+	}
+
+	now = now.Add(15 * time.Second)
+	resp, body := get(t, srv.URL+APIPath+"?api_key=DEMO_KEY")
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "45", resp.Header.Get("Retry-After"))
+	require.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+	require.Contains(t, string(body), "OVER_RATE_LIMIT")
+
+	now = now.Add(45 * time.Second)
+	resp, _ = get(t, srv.URL+APIPath+"?api_key=DEMO_KEY")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_FailEvery(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, Options{FailEvery: 2, FailStatus: http.StatusBadGateway})
+
+	var statuses []int
+	for i := 0; i < 4; i++ {
+		resp, _ := get(t, srv.URL+APIPath+"?api_key=DEMO_KEY")
+		statuses = append(statuses, resp.StatusCode)
+	}
+	require.Equal(t, []int{http.StatusOK, http.StatusBadGateway, http.StatusOK, http.StatusBadGateway}, statuses)
+}
@@ -0,0 +1,739 @@
+// Package config loads the service configuration from a YAML file, environment variables and command-line flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+)
+
+// redacted replaces secrets in the printed configuration.
+const redacted = "REDACTED"
+
+// Config is the configuration of the service.
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	NASA       NASAConfig       `yaml:"nasa"`
+	HTTPClient HTTPClientConfig `yaml:"http_client"`
+	Schedule   ScheduleConfig   `yaml:"schedule"`
+	Sources    []SourceConfig   `yaml:"sources"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Auth       AuthConfig       `yaml:"auth"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+}
+
+// ServerConfig contains the settings of the HTTP server.
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests and background jobs may run after a shutdown signal.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// DatabaseConfig contains the settings of the Postgres connection pool.
+type DatabaseConfig struct {
+	URL             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// NASAConfig contains the settings of the APOD API.
+type NASAConfig struct {
+	BaseURL string `yaml:"base_url"`
+	APIKey  string `yaml:"api_key"`
+	// DownloadHD enables downloading and storing the high definition rendition of each image.
+	DownloadHD bool `yaml:"download_hd"`
+}
+
+// HTTPClientConfig contains the settings of the client used for requests to the APOD API and image hosts.
+type HTTPClientConfig struct {
+	CAFile   string `yaml:"ca_file"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ProxyURL overrides the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	ProxyURL              string        `yaml:"proxy_url"`
+	Timeout               time.Duration `yaml:"timeout"`
+	DialTimeout           time.Duration `yaml:"dial_timeout"`
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"`
+	MaxIdleConns          int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost       int           `yaml:"max_conns_per_host"`
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`
+	MaxResponseSize       int64         `yaml:"max_response_size"`
+}
+
+// ScheduleConfig contains the settings of the daily fetch.
+type ScheduleConfig struct {
+	Cron     string `yaml:"cron"`
+	Timezone string `yaml:"timezone"`
+	// PollInterval enables polling NASA until the picture of the day is published. Zero disables polling.
+	PollInterval time.Duration `yaml:"poll_interval"`
+	PollTimeout  time.Duration `yaml:"poll_timeout"`
+	// OverdueAfter is how long into an APOD day its picture may be missing before the service reports
+	// itself as not ready. Zero disables the check.
+	OverdueAfter time.Duration `yaml:"overdue_after"`
+}
+
+// Types of upstream sources.
+const (
+	SourceTypeAPOD      = "apod"
+	SourceTypeDirectory = "directory"
+)
+
+// SourceConfig contains the settings of an upstream source of daily pictures.
+// Sources can only be configured in the YAML file.
+type SourceConfig struct {
+	// Name is stored with every image of the source and selects it in the API.
+	Name string `yaml:"name"`
+	// Type is apod or directory.
+	Type string `yaml:"type"`
+	// Dir is the directory records are dropped into for the directory type.
+	Dir string `yaml:"dir,omitempty"`
+	// Schedule overrides schedule.cron for the source.
+	Schedule string `yaml:"schedule,omitempty"`
+}
+
+// sourceNamePattern restricts source names to what can be used in URLs and the database unchanged.
+var sourceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// StorageConfig contains the settings of the blob store that keeps image data.
+type StorageConfig struct {
+	// Backend is database, filesystem or s3.
+	Backend string   `yaml:"backend"`
+	Dir     string   `yaml:"dir"`
+	S3      S3Config `yaml:"s3"`
+}
+
+// S3Config contains the settings of the S3-compatible object store.
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	PathStyle bool   `yaml:"path_style"`
+}
+
+// LogConfig contains the settings of the logger.
+type LogConfig struct {
+	Level string `yaml:"level"`
+	// Format is text or json.
+	Format string `yaml:"format"`
+	// Output is stdout or file.
+	Output string `yaml:"output"`
+	File   string `yaml:"file"`
+	// MaxSize is the size in megabytes at which the log file is rotated.
+	MaxSize int `yaml:"max_size"`
+	// MaxBackups and MaxAge bound the number and the age in days of the rotated files kept; zero keeps them all.
+	MaxBackups int  `yaml:"max_backups"`
+	MaxAge     int  `yaml:"max_age"`
+	Compress   bool `yaml:"compress"`
+}
+
+// TracingConfig contains the settings of the trace exporter.
+type TracingConfig struct {
+	// Exporter is none, otlp, stdout or file.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the URL of the OTLP/HTTP collector; the OTEL_EXPORTER_OTLP_* variables apply if it is empty.
+	Endpoint string `yaml:"endpoint"`
+	File     string `yaml:"file"`
+	// SampleRatio is the fraction of the traces started by the service that are recorded. Traces started
+	// by a caller that propagates its trace context follow the caller's sampling decision.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// AuthConfig contains the settings of API key authentication.
+type AuthConfig struct {
+	// AnonymousRead lets requests without an API key read images; admin endpoints always require a key.
+	AnonymousRead bool `yaml:"anonymous_read"`
+}
+
+// RateLimitConfig contains the settings of the per-client rate limits of the API.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Backend is memory, which limits the requests to every replica on its own, or postgres, which shares
+	// the limits between the replicas.
+	Backend string `yaml:"backend"`
+	// TrustForwardedFor identifies the clients without an API key by the last address of the X-Forwarded-For
+	// header rather than the address of the connection. Only set it behind a proxy appending to the header.
+	TrustForwardedFor bool `yaml:"trust_forwarded_for"`
+	// Default applies to every route without a limit in Routes.
+	Default RateLimit `yaml:"default"`
+	// Routes sets the limits of routes by pattern, such as "GET /images/{date}/raw".
+	// Routes can only be configured in the YAML file.
+	Routes map[string]RateLimit `yaml:"routes"`
+	// IP applies to the requests of every IP address across the routes before they are authenticated,
+	// which bounds the API keys a client can try.
+	IP RateLimit `yaml:"ip"`
+}
+
+// RateLimit allows a client Requests per Period on average, in bursts of up to Burst requests.
+type RateLimit struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
+}
+
+// Default returns the configuration used for the settings that are not set explicitly.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":11011",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      2 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		NASA: NASAConfig{
+			BaseURL: "https://api.nasa.gov/planetary/apod",
+		},
+		HTTPClient: HTTPClientConfig{
+			Timeout:               2 * time.Minute,
+			DialTimeout:           10 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+			MaxIdleConns:          20,
+			MaxIdleConnsPerHost:   4,
+			MaxConnsPerHost:       8,
+			IdleConnTimeout:       90 * time.Second,
+			MaxResponseSize:       64 << 20,
+		},
+		Schedule: ScheduleConfig{
+			Cron:         "30 5 * * *",
+			Timezone:     "America/New_York",
+			PollTimeout:  6 * time.Hour,
+			OverdueAfter: 12 * time.Hour,
+		},
+		Sources: []SourceConfig{
+			{Name: "apod", Type: SourceTypeAPOD},
+		},
+		Storage: StorageConfig{
+			Backend: "database",
+		},
+		Log: LogConfig{
+			Level:      "info",
+			Format:     "text",
+			Output:     "stdout",
+			File:       "logs.txt",
+			MaxSize:    100,
+			MaxBackups: 5,
+			MaxAge:     30,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "traces.json",
+			SampleRatio: 1,
+		},
+		Auth: AuthConfig{
+			AnonymousRead: true,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Backend: "memory",
+			Default: RateLimit{Requests: 120, Period: time.Minute, Burst: 30},
+			IP:      RateLimit{Requests: 600, Period: time.Minute, Burst: 120},
+		},
+	}
+}
+
+// setting binds a configuration value to its YAML key, environment variable and command-line flag.
+type setting struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	value any
+}
+
+// settings returns the settings of c that can be overridden by environment variables and flags.
+func (c *Config) settings() []setting {
+	return []setting{
+		{"server.addr", "YA_SERVER_PORT", "server-addr", "address the HTTP server listens on", &c.Server.Addr},
+		{"server.read_header_timeout", "YA_SERVER_READ_HEADER_TIMEOUT", "server-read-header-timeout", "time allowed to read request headers", &c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", "YA_SERVER_READ_TIMEOUT", "server-read-timeout", "time allowed to read a request", &c.Server.ReadTimeout},
+		{"server.write_timeout", "YA_SERVER_WRITE_TIMEOUT", "server-write-timeout", "time allowed to write a response", &c.Server.WriteTimeout},
+		{"server.idle_timeout", "YA_SERVER_IDLE_TIMEOUT", "server-idle-timeout", "time an idle keep-alive connection is kept open", &c.Server.IdleTimeout},
+		{"server.shutdown_timeout", "YA_SERVER_SHUTDOWN_TIMEOUT", "server-shutdown-timeout", "time allowed for in-flight work to finish on shutdown", &c.Server.ShutdownTimeout},
+		{"database.url", "YA_POSTGRES_URL", "db-url", "PostgreSQL connection URL", &c.Database.URL},
+		{"database.max_open_conns", "YA_DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum number of open database connections (0 is unlimited)", &c.Database.MaxOpenConns},
+		{"database.max_idle_conns", "YA_DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum number of idle database connections", &c.Database.MaxIdleConns},
+		{"database.conn_max_lifetime", "YA_DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum time a database connection is reused (0 is unlimited)", &c.Database.ConnMaxLifetime},
+		{"database.conn_max_idle_time", "YA_DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "maximum time a database connection stays idle (0 is unlimited)", &c.Database.ConnMaxIdleTime},
+		{"nasa.base_url", "YA_NASA_BASE_URL", "nasa-base-url", "URL of the APOD API", &c.NASA.BaseURL},
+		{"nasa.api_key", "YA_NASA_API_KEY", "nasa-api-key", "NASA API key", &c.NASA.APIKey},
+		{"nasa.download_hd", "YA_DOWNLOAD_HD", "nasa-download-hd", "also download the HD rendition of each image", &c.NASA.DownloadHD},
+		{"http_client.ca_file", "YA_HTTP_CA_FILE", "http-ca-file", "PEM bundle of additional trusted certificate authorities", &c.HTTPClient.CAFile},
+		{"http_client.cert_file", "YA_HTTP_CERT_FILE", "http-cert-file", "PEM client certificate", &c.HTTPClient.CertFile},
+		{"http_client.key_file", "YA_HTTP_KEY_FILE", "http-key-file", "PEM client certificate key", &c.HTTPClient.KeyFile},
+		{"http_client.proxy_url", "YA_HTTP_PROXY_URL", "http-proxy-url", "proxy for outbound requests (defaults to $HTTPS_PROXY)", &c.HTTPClient.ProxyURL},
+		{"http_client.timeout", "YA_HTTP_TIMEOUT", "http-timeout", "time allowed for an outbound request, including reading the response", &c.HTTPClient.Timeout},
+		{"http_client.dial_timeout", "YA_HTTP_DIAL_TIMEOUT", "http-dial-timeout", "time allowed to connect", &c.HTTPClient.DialTimeout},
+		{"http_client.tls_handshake_timeout", "YA_HTTP_TLS_HANDSHAKE_TIMEOUT", "http-tls-handshake-timeout", "time allowed for the TLS handshake", &c.HTTPClient.TLSHandshakeTimeout},
+		{"http_client.response_header_timeout", "YA_HTTP_RESPONSE_HEADER_TIMEOUT", "http-response-header-timeout", "time allowed to wait for response headers", &c.HTTPClient.ResponseHeaderTimeout},
+		{"http_client.max_idle_conns", "YA_HTTP_MAX_IDLE_CONNS", "http-max-idle-conns", "maximum number of idle outbound connections (0 is unlimited)", &c.HTTPClient.MaxIdleConns},
+		{"http_client.max_idle_conns_per_host", "YA_HTTP_MAX_IDLE_CONNS_PER_HOST", "http-max-idle-conns-per-host", "maximum number of idle outbound connections per host", &c.HTTPClient.MaxIdleConnsPerHost},
+		{"http_client.max_conns_per_host", "YA_HTTP_MAX_CONNS_PER_HOST", "http-max-conns-per-host", "maximum number of outbound connections per host (0 is unlimited)", &c.HTTPClient.MaxConnsPerHost},
+		{"http_client.idle_conn_timeout", "YA_HTTP_IDLE_CONN_TIMEOUT", "http-idle-conn-timeout", "time an idle outbound connection is kept open", &c.HTTPClient.IdleConnTimeout},
+		{"http_client.max_response_size", "YA_HTTP_MAX_RESPONSE_SIZE", "http-max-response-size", "maximum size of an outbound response body in bytes", &c.HTTPClient.MaxResponseSize},
+		{"schedule.cron", "YA_SCHEDULE", "schedule", "cron expression of the daily fetch", &c.Schedule.Cron},
+		{"schedule.timezone", "YA_SCHEDULE_TZ", "schedule-tz", "time zone of the schedule", &c.Schedule.Timezone},
+		{"schedule.poll_interval", "YA_POLL_INTERVAL", "poll-interval", "interval of polling NASA until the picture of the day is published (0 disables polling)", &c.Schedule.PollInterval},
+		{"schedule.poll_timeout", "YA_POLL_TIMEOUT", "poll-timeout", "how long a scheduled fetch polls before giving up", &c.Schedule.PollTimeout},
+		{"schedule.overdue_after", "YA_OVERDUE_AFTER", "overdue-after", "how long into an APOD day its picture may be missing before readiness fails (0 disables)", &c.Schedule.OverdueAfter},
+		{"storage.backend", "YA_STORAGE_BACKEND", "storage-backend", "blob store backend: database, filesystem or s3", &c.Storage.Backend},
+		{"storage.dir", "YA_STORAGE_DIR", "storage-dir", "directory of the filesystem backend", &c.Storage.Dir},
+		{"storage.s3.endpoint", "YA_S3_ENDPOINT", "s3-endpoint", "endpoint of the S3-compatible object store", &c.Storage.S3.Endpoint},
+		{"storage.s3.region", "YA_S3_REGION", "s3-region", "region of the S3 bucket", &c.Storage.S3.Region},
+		{"storage.s3.bucket", "YA_S3_BUCKET", "s3-bucket", "S3 bucket", &c.Storage.S3.Bucket},
+		{"storage.s3.access_key", "YA_S3_ACCESS_KEY", "s3-access-key", "S3 access key", &c.Storage.S3.AccessKey},
+		{"storage.s3.secret_key", "YA_S3_SECRET_KEY", "s3-secret-key", "S3 secret key", &c.Storage.S3.SecretKey},
+		{"storage.s3.path_style", "YA_S3_PATH_STYLE", "s3-path-style", "use path-style bucket addressing", &c.Storage.S3.PathStyle},
+		{"log.level", "YA_LOG_LEVEL", "log-level", "log level: trace, debug, info, warn or error", &c.Log.Level},
+		{"log.format", "YA_LOG_FORMAT", "log-format", "log format: text or json", &c.Log.Format},
+		{"log.output", "YA_LOG_OUTPUT", "log-output", "log output: stdout or file", &c.Log.Output},
+		{"log.file", "YA_LOG_FILE", "log-file", "file the logs are appended to when the output is file", &c.Log.File},
+		{"log.max_size", "YA_LOG_MAX_SIZE", "log-max-size", "size in megabytes at which the log file is rotated", &c.Log.MaxSize},
+		{"log.max_backups", "YA_LOG_MAX_BACKUPS", "log-max-backups", "number of rotated log files kept (0 keeps all)", &c.Log.MaxBackups},
+		{"log.max_age", "YA_LOG_MAX_AGE", "log-max-age", "days rotated log files are kept (0 keeps them regardless of age)", &c.Log.MaxAge},
+		{"log.compress", "YA_LOG_COMPRESS", "log-compress", "gzip rotated log files", &c.Log.Compress},
+		{"tracing.exporter", "YA_TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, otlp, stdout or file", &c.Tracing.Exporter},
+		{"tracing.endpoint", "YA_TRACING_ENDPOINT", "tracing-endpoint", "URL of the OTLP/HTTP collector (defaults to $OTEL_EXPORTER_OTLP_ENDPOINT)", &c.Tracing.Endpoint},
+		{"tracing.file", "YA_TRACING_FILE", "tracing-file", "file the spans are appended to by the file exporter", &c.Tracing.File},
+		{"tracing.sample_ratio", "YA_TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of the traces started by the service that are recorded", &c.Tracing.SampleRatio},
+		{"auth.anonymous_read", "YA_AUTH_ANONYMOUS_READ", "auth-anonymous-read", "serve the image endpoints to requests without an API key", &c.Auth.AnonymousRead},
+		{"rate_limit.enabled", "YA_RATE_LIMIT_ENABLED", "rate-limit-enabled", "throttle the clients that exceed their rate limit", &c.RateLimit.Enabled},
+		{"rate_limit.backend", "YA_RATE_LIMIT_BACKEND", "rate-limit-backend", "rate limit store: memory or postgres (shared by the replicas)", &c.RateLimit.Backend},
+		{"rate_limit.trust_forwarded_for", "YA_RATE_LIMIT_TRUST_FORWARDED_FOR", "rate-limit-trust-forwarded-for", "identify clients by the X-Forwarded-For header set by a proxy", &c.RateLimit.TrustForwardedFor},
+		{"rate_limit.default.requests", "YA_RATE_LIMIT_REQUESTS", "rate-limit-requests", "requests allowed to a client per period on routes without their own limit", &c.RateLimit.Default.Requests},
+		{"rate_limit.default.period", "YA_RATE_LIMIT_PERIOD", "rate-limit-period", "period of the default rate limit", &c.RateLimit.Default.Period},
+		{"rate_limit.default.burst", "YA_RATE_LIMIT_BURST", "rate-limit-burst", "requests a client may send at once under the default rate limit", &c.RateLimit.Default.Burst},
+		{"rate_limit.ip.requests", "YA_RATE_LIMIT_IP_REQUESTS", "rate-limit-ip-requests", "requests allowed to an IP address per period across the routes, before authentication", &c.RateLimit.IP.Requests},
+		{"rate_limit.ip.period", "YA_RATE_LIMIT_IP_PERIOD", "rate-limit-ip-period", "period of the IP address rate limit", &c.RateLimit.IP.Period},
+		{"rate_limit.ip.burst", "YA_RATE_LIMIT_IP_BURST", "rate-limit-ip-burst", "requests an IP address may send at once", &c.RateLimit.IP.Burst},
+	}
+}
+
+// flagValue records the value of a flag so it can be applied after the file and environment.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+// IsBoolFlag allows boolean flags to be set without a value.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// Load registers the configuration flags on flags, parses args and returns the configuration.
+// Settings are taken from, in increasing order of precedence: the defaults, the YAML file named by
+// the -config flag or the YA_CONFIG environment variable, environment variables and flags.
+// The returned configuration is not validated.
+func Load(flags *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	path := flags.String("config", "", "path to the YAML configuration file (defaults to $YA_CONFIG)")
+
+	cfg := Default()
+	values := make(map[string]*flagValue)
+	for _, s := range cfg.settings() {
+		_, isBool := s.value.(*bool)
+		values[s.flag] = &flagValue{isBool: isBool}
+		flags.Var(values[s.flag], s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path == "" {
+		*path = getenv("YA_CONFIG")
+	}
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	for _, s := range cfg.settings() {
+		if value := getenv(s.env); value != "" {
+			if err := setValue(s.value, value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range cfg.settings() {
+		if set[s.flag] {
+			if err := setValue(s.value, values[s.flag].value); err != nil {
+				return nil, fmt.Errorf("invalid -%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// readFile overrides the configuration with the settings of the YAML file. Unknown keys are rejected.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setValue parses s into the value pointed to by ptr.
+func setValue(ptr any, s string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = s
+	case *int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		*p = n
+	case *int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		*p = f
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", s)
+		}
+		*p = d
+	default:
+		panic(fmt.Sprintf("unsupported setting type %T", ptr))
+	}
+	return nil
+}
+
+// fetchCommands lists the commands that fetch entries from the sources and so need their credentials;
+// the empty command serves the API.
+var fetchCommands = map[string]bool{"": true, "backfill": true}
+
+// Validate checks the configuration for the command it is run with, which is empty when the service serves
+// the API, and returns an error describing every invalid setting. Settings of the sources that the command
+// does not fetch from, such as the NASA API key, are only required by the commands that do.
+func (c *Config) Validate(command string) error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		for _, s := range c.settings() {
+			if s.key == key {
+				key = fmt.Sprintf("%s (env %s, flag -%s)", key, s.env, s.flag)
+				break
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Addr == "" {
+		invalid("server.addr", "is required")
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"database.conn_max_lifetime", c.Database.ConnMaxLifetime},
+		{"database.conn_max_idle_time", c.Database.ConnMaxIdleTime},
+		{"http_client.timeout", c.HTTPClient.Timeout},
+		{"http_client.dial_timeout", c.HTTPClient.DialTimeout},
+		{"http_client.tls_handshake_timeout", c.HTTPClient.TLSHandshakeTimeout},
+		{"http_client.response_header_timeout", c.HTTPClient.ResponseHeaderTimeout},
+		{"http_client.idle_conn_timeout", c.HTTPClient.IdleConnTimeout},
+		{"schedule.poll_interval", c.Schedule.PollInterval},
+		{"schedule.overdue_after", c.Schedule.OverdueAfter},
+	} {
+		if d.value < 0 {
+			invalid(d.key, "must not be negative")
+		}
+	}
+
+	if c.Database.URL == "" {
+		invalid("database.url", "is required")
+	}
+	if c.Database.MaxOpenConns < 0 {
+		invalid("database.max_open_conns", "must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		invalid("database.max_idle_conns", "must not be negative")
+	}
+
+	if u, err := url.Parse(c.NASA.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("nasa.base_url", "must be an http or https URL")
+	}
+	if c.NASA.APIKey == "" && c.hasSourceType(SourceTypeAPOD) && fetchCommands[command] {
+		invalid("nasa.api_key", "is required")
+	}
+
+	if (c.HTTPClient.CertFile == "") != (c.HTTPClient.KeyFile == "") {
+		invalid("http_client.cert_file", "must be set together with http_client.key_file")
+	}
+	if c.HTTPClient.ProxyURL != "" {
+		if u, err := url.Parse(c.HTTPClient.ProxyURL); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("http_client.proxy_url", "must be a URL such as http://proxy:3128")
+		}
+	}
+	for _, n := range []struct {
+		key   string
+		value int
+	}{
+		{"http_client.max_idle_conns", c.HTTPClient.MaxIdleConns},
+		{"http_client.max_idle_conns_per_host", c.HTTPClient.MaxIdleConnsPerHost},
+		{"http_client.max_conns_per_host", c.HTTPClient.MaxConnsPerHost},
+	} {
+		if n.value < 0 {
+			invalid(n.key, "must not be negative")
+		}
+	}
+	if c.HTTPClient.MaxResponseSize <= 0 {
+		invalid("http_client.max_response_size", "must be positive")
+	}
+
+	location, err := time.LoadLocation(c.Schedule.Timezone)
+	if err != nil {
+		invalid("schedule.timezone", "unknown time zone %q", c.Schedule.Timezone)
+	} else if _, err := job.ParseSchedule(c.Schedule.Cron, location); err != nil {
+		invalid("schedule.cron", "%v", err)
+	}
+	if c.Schedule.PollTimeout <= 0 {
+		invalid("schedule.poll_timeout", "must be positive")
+	}
+
+	if len(c.Sources) == 0 {
+		invalid("sources", "at least one source is required")
+	}
+	names := make(map[string]bool, len(c.Sources))
+	for i, source := range c.Sources {
+		key := fmt.Sprintf("sources[%d]", i)
+		if !sourceNamePattern.MatchString(source.Name) {
+			invalid(key+".name", "must be 1 to 50 lowercase letters, digits, dashes or underscores, got %q", source.Name)
+		} else if names[source.Name] {
+			invalid(key+".name", "duplicate source %q", source.Name)
+		}
+		names[source.Name] = true
+
+		switch source.Type {
+		case SourceTypeAPOD:
+		case SourceTypeDirectory:
+			if source.Dir == "" {
+				invalid(key+".dir", "is required for the directory type")
+			}
+		default:
+			invalid(key+".type", "must be apod or directory, got %q", source.Type)
+		}
+
+		if source.Schedule != "" && location != nil {
+			if _, err := job.ParseSchedule(source.Schedule, location); err != nil {
+				invalid(key+".schedule", "%v", err)
+			}
+		}
+	}
+
+	switch c.Storage.Backend {
+	case "database":
+	case "filesystem":
+		if c.Storage.Dir == "" {
+			invalid("storage.dir", "is required for the filesystem backend")
+		}
+	case "s3":
+		if c.Storage.S3.Endpoint == "" {
+			invalid("storage.s3.endpoint", "is required for the s3 backend")
+		}
+		if c.Storage.S3.Bucket == "" {
+			invalid("storage.s3.bucket", "is required for the s3 backend")
+		}
+	default:
+		invalid("storage.backend", "must be database, filesystem or s3, got %q", c.Storage.Backend)
+	}
+
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		invalid("log.level", "unknown level %q", c.Log.Level)
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		invalid("log.format", "must be text or json, got %q", c.Log.Format)
+	}
+	switch c.Log.Output {
+	case "stdout":
+	case "file":
+		if c.Log.File == "" {
+			invalid("log.file", "is required for the file output")
+		}
+		if c.Log.MaxSize <= 0 {
+			invalid("log.max_size", "must be positive")
+		}
+		if c.Log.MaxBackups < 0 {
+			invalid("log.max_backups", "must not be negative")
+		}
+		if c.Log.MaxAge < 0 {
+			invalid("log.max_age", "must not be negative")
+		}
+	default:
+		invalid("log.output", "must be stdout or file, got %q", c.Log.Output)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint != "" {
+			if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				invalid("tracing.endpoint", "must be an http or https URL, got %q", c.Tracing.Endpoint)
+			}
+		}
+	case "file":
+		if c.Tracing.File == "" {
+			invalid("tracing.file", "is required for the file exporter")
+		}
+	default:
+		invalid("tracing.exporter", "must be none, otlp, stdout or file, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "postgres" {
+			invalid("rate_limit.backend", "must be memory or postgres, got %q", c.RateLimit.Backend)
+		}
+		c.RateLimit.Default.validate("rate_limit.default", invalid)
+		c.RateLimit.IP.validate("rate_limit.ip", invalid)
+		routes := make([]string, 0, len(c.RateLimit.Routes))
+		for route := range c.RateLimit.Routes {
+			routes = append(routes, route)
+		}
+		sort.Strings(routes)
+		for _, route := range routes {
+			c.RateLimit.Routes[route].validate(fmt.Sprintf("rate_limit.routes[%q]", route), invalid)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate reports the invalid settings of the rate limit configured at key.
+func (l RateLimit) validate(key string, invalid func(key, format string, args ...any)) {
+	if l.Requests <= 0 {
+		invalid(key+".requests", "must be positive")
+	}
+	if l.Period <= 0 {
+		invalid(key+".period", "must be positive")
+	}
+	if l.Burst <= 0 {
+		invalid(key+".burst", "must be positive")
+	}
+}
+
+// hasSourceType reports whether a source of the type is configured.
+func (c *Config) hasSourceType(sourceType string) bool {
+	for _, source := range c.Sources {
+		if source.Type == sourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy of the configuration with the secrets replaced.
+func (c *Config) Redacted() *Config {
+	r := *c
+	if r.Database.URL != "" {
+		// Only the password of a URL, in its user info or its query, is secret; a key=value connection string
+		// is redacted entirely.
+		if u, err := url.Parse(r.Database.URL); err == nil && u.Scheme != "" && u.Host != "" {
+			query := u.Query()
+			for name := range query {
+				if strings.Contains(strings.ToLower(name), "password") {
+					query.Set(name, redacted)
+				}
+			}
+			u.RawQuery = query.Encode()
+			r.Database.URL = u.Redacted()
+		} else {
+			r.Database.URL = redacted
+		}
+	}
+	if r.NASA.APIKey != "" {
+		r.NASA.APIKey = redacted
+	}
+	if r.Storage.S3.SecretKey != "" {
+		r.Storage.S3.SecretKey = redacted
+	}
+	return &r
+}
+
+// WriteYAML writes the configuration to w as YAML.
+func (c *Config) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/EgMeln/YoungAstrologer/internal/httpserver"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+)
+
+// AccessLog wraps next so that every request is logged once it is served, with its method, path, status,
+// duration and the number of bytes of the response body. The method and path are also added to the logger
+// of the request context, so the entries the handlers write for the request carry them too.
+// It must be wrapped by RequestID for the entries to carry the request id.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := logging.WithFields(r.Context(), log.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+		})
+
+		recorder := httpserver.NewResponseRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		logging.FromContext(ctx).WithFields(log.Fields{
+			"status":      recorder.Status(),
+			"duration_ms": time.Since(start).Milliseconds(),
+			"bytes":       recorder.Bytes(),
+		}).Info("Request served")
+	})
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+)
+
+func TestAccessLog(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+	})
+
+	handler := RequestID(AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("Looking up image")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/images/2024-05-18", nil)
+	req.Header.Set(RequestIDHeader, "access-log-test")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entries []*log.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["request_id"] == "access-log-test" {
+			entries = append(entries, entry)
+		}
+	}
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "Looking up image", entries[0].Message)
+	require.Equal(t, log.Fields{"request_id": "access-log-test", "method": http.MethodGet, "path": "/images/2024-05-18"}, entries[0].Data)
+
+	require.Equal(t, "Request served", entries[1].Message)
+	require.Equal(t, http.StatusNotFound, entries[1].Data["status"])
+	require.Equal(t, int64(len("not found")), entries[1].Data["bytes"])
+	require.Contains(t, entries[1].Data, "duration_ms")
+}
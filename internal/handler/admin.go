@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+const (
+	defaultJobRunsLimit = 20
+	maxJobRunsLimit     = 100
+)
+
+// AdminHandler handles HTTP requests for administrative operations.
+type AdminHandler struct {
+	ctx         context.Context
+	runners     map[string]*job.Runner
+	jobService  service.JobService
+	backfilling atomic.Bool
+	wg          sync.WaitGroup
+	now         func() time.Time
+}
+
+// NewAdminHandler creates a new AdminHandler instance with the job runners of the sources by name.
+// Background operations started by the handler are canceled when ctx is done.
+func NewAdminHandler(ctx context.Context, runners map[string]*job.Runner, jobService service.JobService) *AdminHandler {
+	return &AdminHandler{
+		ctx:        ctx,
+		runners:    runners,
+		jobService: jobService,
+		now:        time.Now,
+	}
+}
+
+// Wait blocks until all background operations started by the handler have finished.
+func (ah *AdminHandler) Wait() {
+	ah.wg.Wait()
+}
+
+// backfillResponse is returned when a backfill has been accepted.
+type backfillResponse struct {
+	Source    string `json:"source"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// Backfill handles the HTTP request for starting a backfill of the entries of a source over a date range.
+// The source query parameter selects the source and defaults to APOD.
+// The backfill runs in the background and only one backfill may run at a time.
+func (ah *AdminHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+	if _, _, err := ParseDateRange(startDate, endDate, ah.now()); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = model.DefaultSource
+	}
+	runner, ok := ah.runners[source]
+	if !ok {
+		writeError(w, r, service.InvalidInput("unknown source %q", source))
+		return
+	}
+
+	if !ah.backfilling.CompareAndSwap(false, true) {
+		writeError(w, r, service.Conflict("a backfill is already running"))
+		return
+	}
+
+	// The backfill outlives the request, but its log entries keep the id of the request that started it.
+	ctx := logging.WithFields(ah.ctx, log.Fields{"request_id": RequestIDFromContext(r.Context())})
+	ah.wg.Add(1)
+	go func() {
+		defer ah.wg.Done()
+		defer ah.backfilling.Store(false)
+
+		saved, err := runner.Backfill(ctx, startDate, endDate)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("Backfill of %s from %s to %s finished with error after saving %d images: %v", source, startDate, endDate, saved, err)
+			return
+		}
+		logging.FromContext(ctx).Infof("Backfill of %s from %s to %s finished: %d images saved", source, startDate, endDate, saved)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(backfillResponse{Source: source, StartDate: startDate, EndDate: endDate}); err != nil {
+		logging.FromContext(r.Context()).Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// Jobs handles the HTTP request for listing the most recent job runs.
+// The job query parameter restricts the listing to a single job.
+func (ah *AdminHandler) Jobs(w http.ResponseWriter, r *http.Request) {
+	limit := defaultJobRunsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxJobRunsLimit {
+			writeError(w, r, service.InvalidInput("limit must be an integer between 1 and %d", maxJobRunsLimit))
+			return
+		}
+		limit = n
+	}
+
+	runs, err := ah.jobService.List(r.Context(), r.URL.Query().Get("job"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runs); err != nil {
+		logging.FromContext(r.Context()).Errorf("Failed to encode response: %v", err)
+	}
+}
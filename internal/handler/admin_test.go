@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/provider"
+)
+
+type mockJobService struct {
+	RecordFunc       func(run *model.JobRun) error
+	ListFunc         func(job string, limit int) ([]*model.JobRun, error)
+	HasSucceededFunc func(job, source, apodDate string) (bool, error)
+	LatestFunc       func(job, source, status string) (*model.JobRun, error)
+}
+
+func (m *mockJobService) Record(ctx context.Context, run *model.JobRun) error {
+	return m.RecordFunc(run)
+}
+
+func (m *mockJobService) List(ctx context.Context, job string, limit int) ([]*model.JobRun, error) {
+	return m.ListFunc(job, limit)
+}
+
+func (m *mockJobService) HasSucceeded(ctx context.Context, job, source, apodDate string) (bool, error) {
+	return m.HasSucceededFunc(job, source, apodDate)
+}
+
+func (m *mockJobService) Latest(ctx context.Context, job, source, status string) (*model.JobRun, error) {
+	return m.LatestFunc(job, source, status)
+}
+
+func TestAdminHandler_Backfill(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		method             string
+		query              string
+		running            bool
+		expectedStatusCode int
+	}{
+		{
+			name:               "MethodNotAllowed",
+			method:             http.MethodGet,
+			query:              "start_date=2024-05-01&end_date=2024-05-02",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:               "BadRequest",
+			method:             http.MethodPost,
+			query:              "start_date=2024-05-02&end_date=2024-05-01",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "RangeTooLong",
+			method:             http.MethodPost,
+			query:              "start_date=2020-01-01&end_date=2024-05-02",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "UnknownSource",
+			method:             http.MethodPost,
+			query:              "start_date=2024-05-01&end_date=2024-05-02&source=esa",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "AlreadyRunning",
+			method:             http.MethodPost,
+			query:              "start_date=2024-05-01&end_date=2024-05-02",
+			running:            true,
+			expectedStatusCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apodProvider := provider.NewAPOD(model.DefaultSource, http.DefaultClient, "", "DEMO_KEY")
+			runner := job.NewRunner(NewSourceHandler(&mockImageService{}, apodProvider, false), &mockJobService{}, job.DefaultRetryPolicy, job.PollPolicy{}, 0)
+			adminHandler := NewAdminHandler(context.Background(), map[string]*job.Runner{model.DefaultSource: runner}, &mockJobService{})
+			adminHandler.backfilling.Store(tt.running)
+
+			req, err := http.NewRequest(tt.method, "/admin/backfill?"+tt.query, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			adminHandler.Backfill(recorder, req)
+
+			require.Equal(t, tt.expectedStatusCode, recorder.Code)
+		})
+	}
+}
+
+func TestAdminHandler_Jobs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		query              string
+		listFunc           func(job string, limit int) ([]*model.JobRun, error)
+		expectedStatusCode int
+		expectedRuns       int
+	}{
+		{
+			name:  "Success",
+			query: "job=daily&limit=5",
+			listFunc: func(job string, limit int) ([]*model.JobRun, error) {
+				require.Equal(t, "daily", job)
+				require.Equal(t, 5, limit)
+				return []*model.JobRun{
+					{ID: uuid.New(), Job: "daily", Status: model.JobStatusSuccess, Attempts: 1},
+					{ID: uuid.New(), Job: "daily", Status: model.JobStatusFailed, Attempts: 6, Error: "unexpected status code: 503"},
+				}, nil
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedRuns:       2,
+		},
+		{
+			name: "DefaultLimit",
+			listFunc: func(job string, limit int) ([]*model.JobRun, error) {
+				require.Empty(t, job)
+				require.Equal(t, defaultJobRunsLimit, limit)
+				return nil, nil
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "InvalidLimit",
+			query:              "limit=0",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "ServiceError",
+			listFunc: func(job string, limit int) ([]*model.JobRun, error) {
+				return nil, errors.New("service error")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adminHandler := NewAdminHandler(context.Background(), nil, &mockJobService{ListFunc: tt.listFunc})
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/jobs?"+tt.query, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			adminHandler.Jobs(recorder, req)
+
+			require.Equal(t, tt.expectedStatusCode, recorder.Code)
+
+			if tt.expectedRuns > 0 {
+				var runs []*model.JobRun
+				require.NoError(t, json.NewDecoder(recorder.Body).Decode(&runs))
+				require.Len(t, runs, tt.expectedRuns)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+// APIKeyHeader is the header carrying the API key of a request, as an alternative to a bearer token
+// in the Authorization header.
+const APIKeyHeader = "X-API-Key"
+
+// bearerPrefix starts the Authorization header of a request authenticated with a bearer token.
+const bearerPrefix = "Bearer "
+
+type apiKeyKey struct{}
+
+// Auth authenticates requests with API keys and authorizes them by the role of their key.
+type Auth struct {
+	apiKeyService service.APIKeyService
+	anonymousRead bool
+}
+
+// NewAuth creates a new Auth instance. If anonymousRead is set, requests without an API key are served
+// by the handlers that only require the read role.
+func NewAuth(apiKeyService service.APIKeyService, anonymousRead bool) *Auth {
+	return &Auth{
+		apiKeyService: apiKeyService,
+		anonymousRead: anonymousRead,
+	}
+}
+
+// Require wraps next so that it only serves requests authenticated with an API key that grants the role,
+// which can be retrieved from the request context with APIKeyFromContext. Requests without a key are
+// answered with 401, unless anonymous reads are allowed and the role is read; requests whose key does
+// not grant the role are answered with 403.
+func (a *Auth) Require(role string, next http.Handler) http.Handler {
+	return a.require(role, role == model.RoleRead && a.anonymousRead, next)
+}
+
+// RequireKey wraps next like Require, except that requests without an API key are always answered with 401.
+// It protects the endpoints that only operators and their tools read, even when anonymous reads are allowed.
+func (a *Auth) RequireKey(role string, next http.Handler) http.Handler {
+	return a.require(role, false, next)
+}
+
+// require wraps next so that it only serves requests authenticated with an API key that grants the role
+// or, if allowAnonymous is set, requests without a key.
+func (a *Auth) require(role string, allowAnonymous bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := apiKeyFromRequest(r)
+		if secret == "" {
+			if allowAnonymous {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="YoungAstrologer"`)
+			writeError(w, r, service.Unauthenticated("an API key is required"))
+			return
+		}
+
+		key, err := a.apiKeyService.Authenticate(r.Context(), secret)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="YoungAstrologer", error="invalid_token"`)
+			writeError(w, r, err)
+			return
+		}
+
+		ctx := logging.WithFields(context.WithValue(r.Context(), apiKeyKey{}, key), log.Fields{"api_key": key.Prefix})
+		r = r.WithContext(ctx)
+		if !key.Grants(role) {
+			writeError(w, r, service.Forbidden("the %s role is required", role))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// APIKeyFromContext returns the API key the request the context belongs to was authenticated with,
+// or nil for anonymous requests.
+func APIKeyFromContext(ctx context.Context) *model.APIKey {
+	key, _ := ctx.Value(apiKeyKey{}).(*model.APIKey)
+	return key
+}
+
+// apiKeyFromRequest returns the API key of the request, taken from the X-API-Key header or a bearer token,
+// or an empty string.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(authorization[len(bearerPrefix):])
+	}
+	return ""
+}
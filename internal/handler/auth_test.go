@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+type mockAPIKeyService struct {
+	CreateFunc       func(name, role string) (*model.APIKey, string, error)
+	ListFunc         func() ([]*model.APIKey, error)
+	RevokeFunc       func(id uuid.UUID) error
+	AuthenticateFunc func(secret string) (*model.APIKey, error)
+}
+
+func (m *mockAPIKeyService) Create(ctx context.Context, name, role string) (*model.APIKey, string, error) {
+	return m.CreateFunc(name, role)
+}
+
+func (m *mockAPIKeyService) List(ctx context.Context) ([]*model.APIKey, error) {
+	return m.ListFunc()
+}
+
+func (m *mockAPIKeyService) Revoke(ctx context.Context, id uuid.UUID) error {
+	return m.RevokeFunc(id)
+}
+
+func (m *mockAPIKeyService) Authenticate(ctx context.Context, secret string) (*model.APIKey, error) {
+	return m.AuthenticateFunc(secret)
+}
+
+func TestAuth_Require(t *testing.T) {
+	t.Parallel()
+
+	apiKeyService := &mockAPIKeyService{
+		AuthenticateFunc: func(secret string) (*model.APIKey, error) {
+			switch secret {
+			case "ya_reader":
+				return &model.APIKey{Prefix: "ya_reade", Role: model.RoleRead}, nil
+			case "ya_admin":
+				return &model.APIKey{Prefix: "ya_admin", Role: model.RoleAdmin}, nil
+			}
+			return nil, service.Unauthenticated("API key is invalid or revoked")
+		},
+	}
+
+	tests := []struct {
+		name               string
+		role               string
+		anonymousRead      bool
+		header             string
+		value              string
+		expectedStatusCode int
+		expectedCode       string
+		expectedKey        string
+	}{
+		{
+			name:               "AnonymousRead",
+			role:               model.RoleRead,
+			anonymousRead:      true,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "AnonymousReadDisabled",
+			role:               model.RoleRead,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       codeUnauthenticated,
+		},
+		{
+			name:               "AnonymousAdmin",
+			role:               model.RoleAdmin,
+			anonymousRead:      true,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       codeUnauthenticated,
+		},
+		{
+			name:               "ReadKey",
+			role:               model.RoleRead,
+			header:             APIKeyHeader,
+			value:              "ya_reader",
+			expectedStatusCode: http.StatusOK,
+			expectedKey:        "ya_reade",
+		},
+		{
+			name:               "BearerToken",
+			role:               model.RoleAdmin,
+			header:             "Authorization",
+			value:              "bearer ya_admin",
+			expectedStatusCode: http.StatusOK,
+			expectedKey:        "ya_admin",
+		},
+		{
+			name:               "AdminKeyReads",
+			role:               model.RoleRead,
+			header:             APIKeyHeader,
+			value:              "ya_admin",
+			expectedStatusCode: http.StatusOK,
+			expectedKey:        "ya_admin",
+		},
+		{
+			name:               "ReadKeyForbidden",
+			role:               model.RoleAdmin,
+			header:             APIKeyHeader,
+			value:              "ya_reader",
+			expectedStatusCode: http.StatusForbidden,
+			expectedCode:       codeForbidden,
+		},
+		{
+			name:               "InvalidKey",
+			role:               model.RoleRead,
+			anonymousRead:      true,
+			header:             "Authorization",
+			value:              "Bearer ya_revoked",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       codeUnauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var key *model.APIKey
+			handler := NewAuth(apiKeyService, tt.anonymousRead).Require(tt.role, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				key = APIKeyFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/images", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			require.Equal(t, tt.expectedStatusCode, recorder.Code)
+			if tt.expectedCode != "" {
+				var response errorResponse
+				require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+				require.Equal(t, tt.expectedCode, response.Error.Code)
+			}
+			if recorder.Code == http.StatusUnauthorized {
+				require.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+			}
+			if tt.expectedKey == "" {
+				require.Nil(t, key)
+			} else {
+				require.Equal(t, tt.expectedKey, key.Prefix)
+			}
+		})
+	}
+}
+
+func TestAuth_RequireKey(t *testing.T) {
+	t.Parallel()
+
+	apiKeyService := &mockAPIKeyService{
+		AuthenticateFunc: func(secret string) (*model.APIKey, error) {
+			return &model.APIKey{Prefix: "ya_reade", Role: model.RoleRead}, nil
+		},
+	}
+	handler := NewAuth(apiKeyService, true).RequireKey(model.RoleRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set(APIKeyHeader, "ya_reader")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+const (
+	// dateLayout is the date format used by the APOD API and the images table.
+	dateLayout = "2006-01-02"
+
+	// backfillChunkDays is the maximum number of days requested from the source at once.
+	backfillChunkDays = 30
+
+	// maxBackfillDays is the maximum number of days a backfill requested by an operator may cover.
+	maxBackfillDays = 366
+)
+
+// partialBackfillError is returned by a backfill that could not save the records of some dates. It is not
+// retryable: running the backfill again fetches the whole range once more to save the few records that
+// failed, which is left to the next backfill or daily run.
+type partialBackfillError struct {
+	dates []string
+}
+
+func (e *partialBackfillError) Error() string {
+	return fmt.Sprintf("failed to save %d images, for dates %s", len(e.dates), strings.Join(e.dates, ", "))
+}
+
+// Retryable reports that the backfill should not be retried.
+func (e *partialBackfillError) Retryable() bool {
+	return false
+}
+
+// ParseDateRange parses and validates an inclusive date range in the YYYY-MM-DD format requested for a backfill.
+// The range must lie between the first APOD and the APOD day that is current at now and cover at most
+// maxBackfillDays days.
+func ParseDateRange(startDate, endDate string, now time.Time) (time.Time, time.Time, error) {
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if startDate < firstAPODDate {
+		return time.Time{}, time.Time{}, service.InvalidInput("start date %s is before the first APOD on %s", startDate, firstAPODDate)
+	}
+	if today := job.APODDate(now); endDate > today {
+		return time.Time{}, time.Time{}, service.InvalidInput("end date %s is in the future; the current APOD day is %s", endDate, today)
+	}
+	if days := int(end.Sub(start).Hours()/24) + 1; days > maxBackfillDays {
+		return time.Time{}, time.Time{}, service.InvalidInput("range of %d days exceeds the maximum of %d days", days, maxBackfillDays)
+	}
+
+	return start, end, nil
+}
+
+// parseDateRange parses an inclusive date range in the YYYY-MM-DD format and checks that it is in order.
+func parseDateRange(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := time.Parse(dateLayout, startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, service.InvalidInput("invalid start date %q: expected YYYY-MM-DD", startDate)
+	}
+
+	end, err := time.Parse(dateLayout, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, service.InvalidInput("invalid end date %q: expected YYYY-MM-DD", endDate)
+	}
+
+	if start.After(end) {
+		return time.Time{}, time.Time{}, service.InvalidInput("start date %s is after end date %s", startDate, endDate)
+	}
+
+	return start, end, nil
+}
+
+// Backfill fetches and saves every record of the source between startDate and endDate (inclusive) that is not stored yet.
+// Dates are processed in chronological order and each entry is saved as soon as it is downloaded,
+// so running Backfill again with the same range resumes where an interrupted run stopped.
+// Records that cannot be saved are skipped and their dates are reported by the returned error, which is
+// not retryable, once the whole range has been processed. Records stored concurrently in the meantime are
+// skipped as well. It returns the number of newly saved images.
+func (sh *SourceHandler) Backfill(ctx context.Context, startDate, endDate string) (int, error) {
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+
+	saved := 0
+	var failed []string
+	for chunkStart := start; !chunkStart.After(end); {
+		chunkEnd := chunkStart.AddDate(0, 0, backfillChunkDays-1)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		from, to := chunkStart.Format(dateLayout), chunkEnd.Format(dateLayout)
+		days := int(chunkEnd.Sub(chunkStart).Hours()/24) + 1
+		chunkStart = chunkEnd.AddDate(0, 0, 1)
+
+		storedDates, err := sh.imageService.GetDates(ctx, sh.Source(), from, to)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("Error getting stored dates from %s to %s: %v", from, to, err)
+			return saved, err
+		}
+
+		stored := make(map[string]bool, len(storedDates))
+		for _, date := range storedDates {
+			stored[date] = true
+		}
+		if len(stored) == days {
+			logging.FromContext(ctx).Infof("Skipping backfill from %s to %s: all dates are already stored", from, to)
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("Backfilling %s from %s to %s", sh.Source(), from, to)
+		records, err := sh.provider.Range(ctx, from, to)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("Error fetching %s from %s to %s: %v", sh.Source(), from, to, err)
+			return saved, service.Unavailable(err, "fetching %s from %s to %s", sh.Source(), from, to)
+		}
+
+		for _, record := range records {
+			if stored[record.Date] {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return saved, err
+			}
+
+			err := sh.SaveRecord(ctx, record)
+			if errors.Is(err, service.ErrConflict) {
+				logging.FromContext(ctx).Infof("Skipping image for date %s: already stored", record.Date)
+				continue
+			}
+			if err != nil {
+				logging.FromContext(ctx).Errorf("Error saving image for date %s: %v", record.Date, err)
+				failed = append(failed, record.Date)
+				continue
+			}
+			saved++
+		}
+	}
+
+	if len(failed) > 0 {
+		return saved, &partialBackfillError{dates: failed}
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/provider"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+func newAPODServer(t *testing.T, apods []*model.APOD) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/apod", func(w http.ResponseWriter, r *http.Request) {
+		startDate := r.URL.Query().Get("start_date")
+		endDate := r.URL.Query().Get("end_date")
+
+		var result []*model.APOD
+		for _, apod := range apods {
+			if apod.Date >= startDate && apod.Date <= endDate {
+				result = append(result, &model.APOD{
+					Date:      apod.Date,
+					Title:     apod.Title,
+					MediaType: apod.MediaType,
+					URL:       srv.URL + "/image/" + apod.Date,
+				})
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(result))
+	})
+	mux.HandleFunc("/image/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{0x89, 0x50, 0x4E, 0x47})
+	})
+
+	return srv
+}
+
+func TestParseDateRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		startDate string
+		endDate   string
+		wantErr   bool
+	}{
+		{name: "Valid", startDate: "2024-05-01", endDate: "2024-05-31"},
+		{name: "SingleDay", startDate: "2024-05-01", endDate: "2024-05-01"},
+		{name: "InvalidStart", startDate: "2024-5-1", endDate: "2024-05-31", wantErr: true},
+		{name: "InvalidEnd", startDate: "2024-05-01", endDate: "", wantErr: true},
+		{name: "StartAfterEnd", startDate: "2024-05-31", endDate: "2024-05-01", wantErr: true},
+		{name: "FirstAPOD", startDate: "1995-06-16", endDate: "1995-07-16"},
+		{name: "BeforeFirstAPOD", startDate: "1995-06-15", endDate: "1995-07-16", wantErr: true},
+		{name: "CurrentAPODDay", startDate: "2024-05-01", endDate: "2024-05-31"},
+		{name: "Future", startDate: "2024-05-01", endDate: "2024-06-01", wantErr: true},
+		{name: "MaxDays", startDate: "2023-06-01", endDate: "2024-05-31"},
+		{name: "TooManyDays", startDate: "2023-05-31", endDate: "2024-05-31", wantErr: true},
+	}
+
+	// 2024-06-01 03:00 UTC is still 2024-05-31 in the APOD time zone.
+	now := time.Date(2024, 6, 1, 3, 0, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseDateRange(tt.startDate, tt.endDate, now)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSourceHandler_Backfill(t *testing.T) {
+	t.Parallel()
+
+	var apods []*model.APOD
+	for _, date := range []string{"2024-04-30", "2024-05-01", "2024-05-02", "2024-05-03", "2024-06-15"} {
+		apods = append(apods, &model.APOD{Date: date, Title: "Nebula " + date, MediaType: "image"})
+	}
+	srv := newAPODServer(t, apods)
+
+	var mu sync.Mutex
+	var saved []string
+	imageService := &mockImageService{
+		GetDatesFunc: func(source, from, to string) ([]string, error) {
+			require.Equal(t, model.DefaultSource, source)
+			if from <= "2024-05-02" && to >= "2024-05-02" {
+				return []string{"2024-05-02"}, nil
+			}
+			return nil, nil
+		},
+		SaveFunc: func(image *model.Image) error {
+			mu.Lock()
+			defer mu.Unlock()
+			require.Equal(t, []byte{0x89, 0x50, 0x4E, 0x47}, image.Data)
+			require.Equal(t, model.DefaultSource, image.Source)
+			saved = append(saved, image.Date)
+			if image.Date == "2024-06-15" {
+				// Stored concurrently since the stored dates were read.
+				return service.Conflict("%s image for date %s is already stored", image.Source, image.Date)
+			}
+			return nil
+		},
+	}
+
+	sourceHandler := NewSourceHandler(imageService, provider.NewAPOD(model.DefaultSource, srv.Client(), srv.URL+"/apod", "DEMO_KEY"), false)
+
+	count, err := sourceHandler.Backfill(context.Background(), "2024-05-01", "2024-06-30")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Equal(t, []string{"2024-05-01", "2024-05-03", "2024-06-15"}, saved)
+}
+
+func TestSourceHandler_BackfillSkipsStoredChunks(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	}))
+	t.Cleanup(srv.Close)
+
+	imageService := &mockImageService{
+		GetDatesFunc: func(source, from, to string) ([]string, error) {
+			return []string{"2024-05-01", "2024-05-02"}, nil
+		},
+	}
+
+	sourceHandler := NewSourceHandler(imageService, provider.NewAPOD(model.DefaultSource, srv.Client(), srv.URL, "DEMO_KEY"), false)
+
+	count, err := sourceHandler.Backfill(context.Background(), "2024-05-01", "2024-05-02")
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestSourceHandler_BackfillFailures(t *testing.T) {
+	t.Parallel()
+
+	var apods []*model.APOD
+	for _, date := range []string{"2024-05-01", "2024-05-02", "2024-05-03"} {
+		apods = append(apods, &model.APOD{Date: date, Title: "Nebula " + date, MediaType: "image"})
+	}
+	srv := newAPODServer(t, apods)
+
+	imageService := &mockImageService{
+		GetDatesFunc: func(source, from, to string) ([]string, error) {
+			return nil, nil
+		},
+		SaveFunc: func(image *model.Image) error {
+			if image.Date == "2024-05-02" {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	}
+
+	sourceHandler := NewSourceHandler(imageService, provider.NewAPOD(model.DefaultSource, srv.Client(), srv.URL+"/apod", "DEMO_KEY"), false)
+
+	count, err := sourceHandler.Backfill(context.Background(), "2024-05-01", "2024-05-03")
+	require.EqualError(t, err, "failed to save 1 images, for dates 2024-05-02")
+	require.Equal(t, 2, count)
+
+	var withRetryable interface{ Retryable() bool }
+	require.ErrorAs(t, err, &withRetryable)
+	require.False(t, withRetryable.Retryable())
+}
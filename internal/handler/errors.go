@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+// RequestIDHeader is the header carrying the id of a request in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the length above which a request id provided by the client is replaced.
+const maxRequestIDLength = 128
+
+// Error codes of the error responses.
+const (
+	codeNotFound            = "not_found"
+	codeInvalidInput        = "invalid_input"
+	codeConflict            = "conflict"
+	codeUnauthenticated     = "unauthenticated"
+	codeForbidden           = "forbidden"
+	codeRateLimited         = "rate_limited"
+	codeUpstreamUnavailable = "upstream_unavailable"
+	codeMethodNotAllowed    = "method_not_allowed"
+	codeInternal            = "internal"
+)
+
+type requestIDKey struct{}
+
+// RequestID wraps next so that every request has an id, which is taken from the X-Request-ID header
+// if the client provided a valid one and generated otherwise. The id is echoed in the response header
+// and can be retrieved from the request context with RequestIDFromContext. It is also added to the logger
+// of the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := logging.WithFields(context.WithValue(r.Context(), requestIDKey{}, id), log.Fields{"request_id": id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the id of the request the context belongs to, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether a request id provided by the client is short and printable.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+// errorBody describes the error of a failed request.
+type errorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorResponse is the response body of every failed request.
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+// writeError writes the error response for err, which is mapped to a status code by its kind.
+// The message of service errors is returned to the client; any other error is reported as
+// an internal error without details.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := http.StatusInternalServerError, codeInternal
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		status, code = http.StatusNotFound, codeNotFound
+	case errors.Is(err, service.ErrInvalidInput):
+		status, code = http.StatusBadRequest, codeInvalidInput
+	case errors.Is(err, service.ErrConflict):
+		status, code = http.StatusConflict, codeConflict
+	case errors.Is(err, service.ErrUnauthenticated):
+		status, code = http.StatusUnauthorized, codeUnauthenticated
+	case errors.Is(err, service.ErrForbidden):
+		status, code = http.StatusForbidden, codeForbidden
+	case errors.Is(err, service.ErrRateLimited):
+		status, code = http.StatusTooManyRequests, codeRateLimited
+	case errors.Is(err, service.ErrUnavailable):
+		status, code = http.StatusServiceUnavailable, codeUpstreamUnavailable
+	}
+
+	message := "internal server error"
+	var domainErr *service.Error
+	if status != http.StatusInternalServerError && errors.As(err, &domainErr) {
+		message = domainErr.Message()
+	}
+
+	entry := logging.FromContext(r.Context()).WithField("status", status)
+	if status >= http.StatusInternalServerError {
+		entry.Errorf("Request failed: %v", err)
+	} else {
+		entry.Warnf("Request failed: %v", err)
+	}
+
+	writeErrorResponse(w, r, status, code, message)
+}
+
+// writeErrorResponse writes an error response with the status, code and message.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	body := errorResponse{Error: errorBody{Code: code, Message: message, RequestID: RequestIDFromContext(r.Context())}}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.FromContext(r.Context()).Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// writeMethodNotAllowed writes the error response for a request with a method other than allowed.
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeErrorResponse(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method "+r.Method+" is not allowed")
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+func TestWriteError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		err                error
+		expectedStatusCode int
+		expectedCode       string
+		expectedMessage    string
+	}{
+		{
+			name:               "NotFound",
+			err:                service.NotFound("no apod image for date 2024-05-18"),
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       "not_found",
+			expectedMessage:    "no apod image for date 2024-05-18",
+		},
+		{
+			name:               "InvalidInput",
+			err:                service.InvalidInput("limit must be positive"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "invalid_input",
+			expectedMessage:    "limit must be positive",
+		},
+		{
+			name:               "Conflict",
+			err:                service.Conflict("a backfill is already running"),
+			expectedStatusCode: http.StatusConflict,
+			expectedCode:       "conflict",
+			expectedMessage:    "a backfill is already running",
+		},
+		{
+			name:               "Unauthenticated",
+			err:                service.Unauthenticated("API key is invalid or revoked"),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       "unauthenticated",
+			expectedMessage:    "API key is invalid or revoked",
+		},
+		{
+			name:               "Forbidden",
+			err:                service.Forbidden("the admin role is required"),
+			expectedStatusCode: http.StatusForbidden,
+			expectedCode:       "forbidden",
+			expectedMessage:    "the admin role is required",
+		},
+		{
+			name:               "RateLimited",
+			err:                service.RateLimited("rate limit exceeded, retry in 2 seconds"),
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedCode:       "rate_limited",
+			expectedMessage:    "rate limit exceeded, retry in 2 seconds",
+		},
+		{
+			name:               "Unavailable",
+			err:                fmt.Errorf("get image: %w", service.Unavailable(errors.New("connection refused"), "blob store is unavailable")),
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedCode:       "upstream_unavailable",
+			expectedMessage:    "blob store is unavailable",
+		},
+		{
+			name:               "Internal",
+			err:                errors.New("pq: connection reset"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       "internal",
+			expectedMessage:    "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, tt.err)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/images", nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			require.Equal(t, tt.expectedStatusCode, recorder.Code)
+			require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+			var response errorResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			require.Equal(t, errorBody{Code: tt.expectedCode, Message: tt.expectedMessage, RequestID: "req-1"}, response.Error)
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{name: "Provided", requestID: "3f2a9c"},
+		{name: "Missing", generated: true},
+		{name: "TooLong", requestID: strings.Repeat("a", maxRequestIDLength+1), generated: true},
+		{name: "NotPrintable", requestID: "id with spaces", generated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fromContext = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/images", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			requestID := recorder.Header().Get(RequestIDHeader)
+			require.Equal(t, requestID, fromContext)
+			if tt.generated {
+				require.Len(t, requestID, 36)
+			} else {
+				require.Equal(t, tt.requestID, requestID)
+			}
+		})
+	}
+}
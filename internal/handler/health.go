@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+// Results of the readiness checks.
+const (
+	readyStatus    = "ready"
+	notReadyStatus = "not_ready"
+	checkOK        = "ok"
+)
+
+// HealthSource describes a source whose fetches are reported by the status endpoint.
+type HealthSource struct {
+	Name string
+	// Schedule is the schedule of the daily fetch of the source.
+	Schedule *job.Schedule
+	// Daily is set for sources that publish an entry every APOD day, which readiness requires to be fetched in time.
+	Daily bool
+}
+
+// HealthHandler handles HTTP requests for the liveness, readiness and status of the service.
+type HealthHandler struct {
+	healthService service.HealthService
+	jobService    service.JobService
+	imageService  service.ImageService
+	sources       []HealthSource
+	version       string
+	overdueAfter  time.Duration
+	startedAt     time.Time
+	now           func() time.Time
+}
+
+// NewHealthHandler creates a new HealthHandler instance reporting on the sources and the build version.
+// Readiness fails once the entry of the current APOD day of a daily source is still missing overdueAfter
+// after the day started; zero disables the check.
+func NewHealthHandler(healthService service.HealthService, jobService service.JobService, imageService service.ImageService,
+	sources []HealthSource, version string, overdueAfter time.Duration) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+		jobService:    jobService,
+		imageService:  imageService,
+		sources:       sources,
+		version:       version,
+		overdueAfter:  overdueAfter,
+		startedAt:     time.Now(),
+		now:           time.Now,
+	}
+}
+
+// livenessResponse is returned while the process is able to serve requests.
+type livenessResponse struct {
+	Status string `json:"status"`
+}
+
+// readinessResponse reports the outcome of every readiness check by name.
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// jobError describes the latest failed job run of a source.
+type jobError struct {
+	Job   string    `json:"job"`
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+// sourceStatus reports the fetches of a source.
+type sourceStatus struct {
+	Name string `json:"name"`
+	// LastSuccessAt and LastSuccessDate are the finish time and the APOD date of the latest successful daily fetch.
+	LastSuccessAt   *time.Time `json:"last_success_at,omitempty"`
+	LastSuccessDate string     `json:"last_success_date,omitempty"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty"`
+	LastError       *jobError  `json:"last_error,omitempty"`
+}
+
+// statusResponse reports the state of the service.
+type statusResponse struct {
+	Version       string         `json:"version"`
+	StartedAt     time.Time      `json:"started_at"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Images        int64          `json:"images"`
+	Sources       []sourceStatus `json:"sources"`
+}
+
+// Liveness handles the HTTP request for checking that the process is alive. It always succeeds.
+func (hh *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, livenessResponse{Status: checkOK})
+}
+
+// Readiness handles the HTTP request for checking that the service can serve traffic: the database is reachable
+// and migrated, and the entries of the daily sources are not overdue. It responds with 503 if any check fails.
+func (hh *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	response := readinessResponse{Status: readyStatus, Checks: make(map[string]string)}
+	check := func(name string, err error) {
+		response.Checks[name] = checkOK
+		if err != nil {
+			response.Status = notReadyStatus
+			response.Checks[name] = message(err)
+			logging.FromContext(r.Context()).Warnf("Readiness check %s failed: %v", name, err)
+		}
+	}
+
+	check("database", hh.healthService.CheckDatabase(r.Context()))
+	if hh.overdueAfter > 0 {
+		for _, source := range hh.sources {
+			if source.Daily {
+				check("fetch:"+source.Name, hh.checkOverdue(r, source.Name))
+			}
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status != readyStatus {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, r, status, response)
+}
+
+// checkOverdue returns an error if the entry of the current APOD day of the source was not fetched
+// although the day started more than overdueAfter ago.
+func (hh *HealthHandler) checkOverdue(r *http.Request, source string) error {
+	now := hh.now()
+	if now.Sub(job.APODDayStart(now)) <= hh.overdueAfter {
+		return nil
+	}
+
+	date := job.APODDate(now)
+	fetched, err := hh.jobService.HasSucceeded(r.Context(), job.DailyJob, source, date)
+	if err != nil {
+		return service.Unavailable(err, "job history is unavailable")
+	}
+	if !fetched {
+		return service.NotFound("entry for %s is overdue by more than %s", date, hh.overdueAfter)
+	}
+	return nil
+}
+
+// Status handles the HTTP request for the state of the service: its build version, uptime, the number
+// of stored images and, for every source, the latest successful daily fetch, the next scheduled fetch
+// and the latest failed job run.
+func (hh *HealthHandler) Status(w http.ResponseWriter, r *http.Request) {
+	now := hh.now()
+	response := statusResponse{
+		Version:       hh.version,
+		StartedAt:     hh.startedAt.UTC(),
+		UptimeSeconds: int64(now.Sub(hh.startedAt).Seconds()),
+		Sources:       make([]sourceStatus, 0, len(hh.sources)),
+	}
+
+	stats, err := hh.imageService.GetStats(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.Images = stats.Images
+
+	for _, source := range hh.sources {
+		status := sourceStatus{Name: source.Name}
+		if source.Schedule != nil {
+			if next := source.Schedule.Next(now); !next.IsZero() {
+				status.NextRunAt = &next
+			}
+		}
+
+		success, err := hh.latestRun(r, job.DailyJob, source.Name, model.JobStatusSuccess)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if success != nil {
+			status.LastSuccessAt = &success.FinishedAt
+			status.LastSuccessDate = success.APODDate
+		}
+
+		failure, err := hh.latestRun(r, "", source.Name, model.JobStatusFailed)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if failure != nil {
+			status.LastError = &jobError{Job: failure.Job, At: failure.FinishedAt, Error: failure.Error}
+		}
+
+		response.Sources = append(response.Sources, status)
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// latestRun retrieves the latest run of the job of the source with the status, or nil if there is none.
+func (hh *HealthHandler) latestRun(r *http.Request, jobName, source, status string) (*model.JobRun, error) {
+	run, err := hh.jobService.Latest(r.Context(), jobName, source, status)
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, nil
+	}
+	return run, err
+}
+
+// message returns the description of a failed readiness check: the message of domain errors and
+// a generic description of any other error, whose details are only logged.
+func message(err error) string {
+	var domainErr *service.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Message()
+	}
+	return "check failed"
+}
+
+// writeJSON writes v as the JSON response body with the status code.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	encodeJSON(w, r, v)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+type mockHealthService struct {
+	CheckDatabaseFunc func() error
+}
+
+func (m *mockHealthService) CheckDatabase(ctx context.Context) error {
+	return m.CheckDatabaseFunc()
+}
+
+func TestHealthHandler_Liveness(t *testing.T) {
+	t.Parallel()
+
+	healthHandler := NewHealthHandler(nil, nil, nil, nil, "v1.2.3", 0)
+
+	recorder := httptest.NewRecorder()
+	healthHandler.Liveness(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status": "ok"}`, recorder.Body.String())
+}
+
+func TestHealthHandler_Readiness(t *testing.T) {
+	t.Parallel()
+
+	// 2024-05-18 10:00 in New York, ten hours into the APOD day.
+	now := time.Date(2024, 5, 18, 14, 0, 0, 0, time.UTC)
+	sources := []HealthSource{{Name: model.DefaultSource, Daily: true}, {Name: "drop"}}
+
+	tests := []struct {
+		name               string
+		databaseErr        error
+		overdueAfter       time.Duration
+		fetched            bool
+		expectedStatusCode int
+		expectedChecks     map[string]string
+	}{
+		{
+			name:               "Ready",
+			overdueAfter:       12 * time.Hour,
+			expectedStatusCode: http.StatusOK,
+			expectedChecks:     map[string]string{"database": "ok", "fetch:apod": "ok"},
+		},
+		{
+			name:               "Fetched",
+			overdueAfter:       6 * time.Hour,
+			fetched:            true,
+			expectedStatusCode: http.StatusOK,
+			expectedChecks:     map[string]string{"database": "ok", "fetch:apod": "ok"},
+		},
+		{
+			name:               "Overdue",
+			overdueAfter:       6 * time.Hour,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedChecks:     map[string]string{"database": "ok", "fetch:apod": "entry for 2024-05-18 is overdue by more than 6h0m0s"},
+		},
+		{
+			name:               "OverdueCheckDisabled",
+			expectedStatusCode: http.StatusOK,
+			expectedChecks:     map[string]string{"database": "ok"},
+		},
+		{
+			name:               "DatabaseUnavailable",
+			databaseErr:        service.Unavailable(errors.New("connection refused"), "database is unreachable"),
+			overdueAfter:       12 * time.Hour,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedChecks:     map[string]string{"database": "database is unreachable", "fetch:apod": "ok"},
+		},
+		{
+			name:               "CheckError",
+			databaseErr:        errors.New("pq: password authentication failed for user \"postgres\""),
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedChecks:     map[string]string{"database": "check failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			healthService := &mockHealthService{
+				CheckDatabaseFunc: func() error {
+					return tt.databaseErr
+				},
+			}
+			jobService := &mockJobService{
+				HasSucceededFunc: func(jobName, source, apodDate string) (bool, error) {
+					require.Equal(t, job.DailyJob, jobName)
+					require.Equal(t, model.DefaultSource, source)
+					require.Equal(t, "2024-05-18", apodDate)
+					return tt.fetched, nil
+				},
+			}
+			healthHandler := NewHealthHandler(healthService, jobService, nil, sources, "v1.2.3", tt.overdueAfter)
+			healthHandler.now = func() time.Time { return now }
+
+			recorder := httptest.NewRecorder()
+			healthHandler.Readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			require.Equal(t, tt.expectedStatusCode, recorder.Code)
+
+			var response readinessResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			require.Equal(t, tt.expectedChecks, response.Checks)
+		})
+	}
+}
+
+func TestHealthHandler_Status(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 18, 18, 0, 0, 0, time.UTC)
+	finishedAt := time.Date(2024, 5, 18, 9, 31, 0, 0, time.UTC)
+	schedule, err := job.ParseSchedule("30 5 * * *", time.UTC)
+	require.NoError(t, err)
+
+	imageService := &mockImageService{
+		GetStatsFunc: func() (*model.StorageStats, error) {
+			return &model.StorageStats{Images: 42, Bytes: 1 << 20}, nil
+		},
+	}
+	jobService := &mockJobService{
+		LatestFunc: func(jobName, source, status string) (*model.JobRun, error) {
+			if source != model.DefaultSource {
+				return nil, service.NotFound("no matching runs of %s", source)
+			}
+			if status == model.JobStatusSuccess {
+				require.Equal(t, job.DailyJob, jobName)
+				return &model.JobRun{Job: jobName, APODDate: "2024-05-18", FinishedAt: finishedAt, Status: status}, nil
+			}
+			require.Empty(t, jobName)
+			return &model.JobRun{Job: job.BackfillJob, FinishedAt: finishedAt.Add(-time.Hour), Status: status, Error: "unexpected status code: 503"}, nil
+		},
+	}
+	sources := []HealthSource{{Name: model.DefaultSource, Schedule: schedule, Daily: true}, {Name: "drop"}}
+	healthHandler := NewHealthHandler(nil, jobService, imageService, sources, "v1.2.3", 12*time.Hour)
+	healthHandler.startedAt = now.Add(-90 * time.Minute)
+	healthHandler.now = func() time.Time { return now }
+
+	recorder := httptest.NewRecorder()
+	healthHandler.Status(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{
+		"version": "v1.2.3",
+		"started_at": "2024-05-18T16:30:00Z",
+		"uptime_seconds": 5400,
+		"images": 42,
+		"sources": [
+			{
+				"name": "apod",
+				"last_success_at": "2024-05-18T09:31:00Z",
+				"last_success_date": "2024-05-18",
+				"next_run_at": "2024-05-19T05:30:00Z",
+				"last_error": {"job": "backfill", "at": "2024-05-18T08:31:00Z", "error": "unexpected status code: 503"}
+			},
+			{"name": "drop"}
+		]
+	}`, recorder.Body.String())
+
+	imageService.GetStatsFunc = func() (*model.StorageStats, error) {
+		return nil, errors.New("connection refused")
+	}
+	recorder = httptest.NewRecorder()
+	healthHandler.Status(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
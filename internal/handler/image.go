@@ -2,68 +2,445 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
-	log "github.com/sirupsen/logrus"
-
+	"github.com/EgMeln/YoungAstrologer/internal/imaging"
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
 	"github.com/EgMeln/YoungAstrologer/internal/service"
+	"github.com/EgMeln/YoungAstrologer/internal/tracing"
+)
+
+// firstAPODDate is the date of the first Astronomy Picture of the Day; no image can be older.
+const firstAPODDate = "1995-06-16"
+
+// Date aliases accepted in place of a YYYY-MM-DD date.
+const (
+	dateToday     = "today"
+	dateYesterday = "yesterday"
+	dateRandom    = "random"
 )
 
 // ImageHandler handles HTTP requests related to images.
 type ImageHandler struct {
 	imageService service.ImageService
+	now          func() time.Time
 }
 
 // NewImageHandler creates a new ImageHandler instance.
 func NewImageHandler(imageService service.ImageService) *ImageHandler {
 	return &ImageHandler{
 		imageService: imageService,
+		now:          time.Now,
+	}
+}
+
+// parseDate parses a date in the strict YYYY-MM-DD format, or one of the today and yesterday aliases,
+// which refer to APOD days. Dates before the first APOD and after the current APOD day are rejected.
+func parseDate(value string, now time.Time) (string, error) {
+	today := job.APODDate(now)
+
+	switch value {
+	case "":
+		return "", service.InvalidInput("date is required")
+	case dateToday:
+		return today, nil
+	case dateYesterday:
+		t, _ := time.Parse(dateLayout, today)
+		return t.AddDate(0, 0, -1).Format(dateLayout), nil
+	}
+
+	t, err := time.Parse(dateLayout, value)
+	if err != nil || t.Format(dateLayout) != value {
+		return "", service.InvalidInput("date %q must be in the YYYY-MM-DD format or one of %s, %s and %s", value, dateToday, dateYesterday, dateRandom)
+	}
+	if value < firstAPODDate {
+		return "", service.InvalidInput("date %s is before the first APOD on %s", value, firstAPODDate)
+	}
+	if value > today {
+		return "", service.InvalidInput("date %s is in the future; the current APOD day is %s", value, today)
+	}
+	return value, nil
+}
+
+// resolveDate resolves the date parameter of a request for an image of the source.
+// The random alias picks the date of a random stored image.
+func (ih *ImageHandler) resolveDate(ctx context.Context, source, value string) (string, error) {
+	if value == dateRandom {
+		return ih.imageService.GetRandomDate(ctx, source)
 	}
+	return parseDate(value, ih.now())
 }
 
-// GetByDate handles the HTTP request for retrieving an image by date.
+// sourceParam returns the source selected by the source query parameter, which defaults to APOD.
+func sourceParam(query url.Values) string {
+	if source := query.Get("source"); source != "" {
+		return source
+	}
+	return model.DefaultSource
+}
+
+// GetByDate handles the HTTP request for retrieving an image of a source by date.
+// The date is either in the YYYY-MM-DD format or one of the today, yesterday and random aliases.
 func (ih *ImageHandler) GetByDate(w http.ResponseWriter, r *http.Request) {
-	date := r.URL.Query().Get("date")
-	if date == "" {
-		log.Warn("Date parameter is missing")
-		http.Error(w, "Date parameter is required", http.StatusBadRequest)
+	source := sourceParam(r.URL.Query())
+	date, err := ih.resolveDate(r.Context(), source, r.URL.Query().Get("date"))
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	image, err := ih.imageService.GetByDate(date)
+	image, err := ih.imageService.GetByDate(r.Context(), source, date)
 	if err != nil {
-		log.Errorf("Failed to get image by date: %v", err)
-		http.Error(w, "Failed to get image by date", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
-	if image == nil {
-		log.Errorf("Image not found for date: %s", date)
-		http.Error(w, "Image not found", http.StatusNotFound)
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, image)
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// imageLinks contains links to the resources of a listed image.
+// Raw points to the picture of an image or the thumbnail of a video and is omitted when no data is stored.
+type imageLinks struct {
+	Self  string `json:"self"`
+	Raw   string `json:"raw,omitempty"`
+	RawHD string `json:"raw_hd,omitempty"`
+}
+
+// imageListItem is a single entry of the image listing.
+type imageListItem struct {
+	*model.ImageMetadata
+	Links imageLinks `json:"links"`
+}
+
+// listLinks contains pagination links of the image listing.
+type listLinks struct {
+	Next string `json:"next,omitempty"`
+}
+
+// imageListResponse is the response body of the image listing.
+type imageListResponse struct {
+	Images     []imageListItem `json:"images"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Links      listLinks       `json:"links"`
+}
+
+// parseListOptions parses pagination, filtering and sorting query parameters.
+func parseListOptions(query url.Values) (model.ListOptions, error) {
+	opts := model.ListOptions{
+		Source: sourceParam(query),
+		Limit:  defaultListLimit,
+		After:  query.Get("after"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Order:  model.SortAsc,
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxListLimit {
+			return opts, service.InvalidInput("limit must be an integer between 1 and %d", maxListLimit)
+		}
+		opts.Limit = n
+	}
+
+	for name, value := range map[string]string{"after": opts.After, "from": opts.From, "to": opts.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return opts, service.InvalidInput("%s must be a date in the YYYY-MM-DD format", name)
+		}
+	}
+
+	switch order := model.SortOrder(query.Get("order")); order {
+	case "":
+	case model.SortAsc, model.SortDesc:
+		opts.Order = order
+	default:
+		return opts, service.InvalidInput("order must be either %q or %q", model.SortAsc, model.SortDesc)
+	}
+
+	switch orientation := model.Orientation(query.Get("orientation")); orientation {
+	case "":
+	case model.OrientationLandscape, model.OrientationPortrait, model.OrientationSquare:
+		opts.Orientation = orientation
+	default:
+		return opts, service.InvalidInput("orientation must be one of %q, %q and %q",
+			model.OrientationLandscape, model.OrientationPortrait, model.OrientationSquare)
+	}
+
+	for name, dest := range map[string]*int{"min_width": &opts.MinWidth, "min_height": &opts.MinHeight} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return opts, service.InvalidInput("%s must be a positive integer", name)
+		}
+		*dest = n
+	}
+
+	return opts, nil
+}
+
+// GetAll handles the HTTP request for listing image metadata of a source.
+// Results are paginated with the limit and after query parameters, can be filtered by the from and to dates,
+// the orientation and the min_width and min_height dimensions, and are sorted by date according to the order parameter.
+func (ih *ImageHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(image); err != nil {
-		log.Errorf("Failed to encode response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	pageSize := opts.Limit
+	opts.Limit++
+	images, err := ih.imageService.List(r.Context(), opts)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+
+	response := imageListResponse{Images: make([]imageListItem, 0, len(images))}
+	if len(images) > pageSize {
+		images = images[:pageSize]
+		response.NextCursor = images[len(images)-1].Date
+
+		query := r.URL.Query()
+		query.Set("after", response.NextCursor)
+		response.Links.Next = r.URL.Path + "?" + query.Encode()
+	}
+	for _, image := range images {
+		response.Images = append(response.Images, imageListItem{
+			ImageMetadata: image,
+			Links:         newImageLinks(image, opts.Source),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, response)
 }
 
-// GetAll handles the HTTP request for retrieving all images.
-func (ih *ImageHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	images, err := ih.imageService.GetAll()
+// newImageLinks returns the links to the resources of a listed image of the source.
+func newImageLinks(image *model.ImageMetadata, source string) imageLinks {
+	sourceQuery := ""
+	if source != model.DefaultSource {
+		sourceQuery = "source=" + url.QueryEscape(source)
+	}
+
+	links := imageLinks{Self: "/images/date?" + joinQuery("date="+image.Date, sourceQuery)}
+	if image.Size > 0 {
+		links.Raw = "/images/" + image.Date + "/raw" + prefixQuery(sourceQuery)
+	}
+	if image.HDSize > 0 {
+		links.RawHD = "/images/" + image.Date + "/raw?" + joinQuery("size=hd", sourceQuery)
+	}
+	return links
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// maxSearchOffset bounds how deep results can be paged, as every page ranks all skipped matches again.
+	maxSearchOffset = 1000
+)
+
+// searchResultItem is a single entry of the search results.
+type searchResultItem struct {
+	*model.SearchResult
+	Links imageLinks `json:"links"`
+}
+
+// searchResponse is the response body of the image search.
+type searchResponse struct {
+	Results    []searchResultItem `json:"results"`
+	NextOffset int                `json:"next_offset,omitempty"`
+	Links      listLinks          `json:"links"`
+}
+
+// parseSearchOptions parses the search text and pagination query parameters.
+func parseSearchOptions(query url.Values) (model.SearchOptions, error) {
+	opts := model.SearchOptions{
+		Source: sourceParam(query),
+		Query:  strings.TrimSpace(query.Get("q")),
+		Limit:  defaultSearchLimit,
+	}
+	if opts.Query == "" {
+		return opts, service.InvalidInput("q is required")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return opts, service.InvalidInput("limit must be an integer between 1 and %d", maxSearchLimit)
+		}
+		opts.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 || n > maxSearchOffset {
+			return opts, service.InvalidInput("offset must be an integer between 0 and %d", maxSearchOffset)
+		}
+		opts.Offset = n
+	}
+
+	return opts, nil
+}
+
+// Search handles the HTTP request for searching the titles and explanations of the images of a source.
+// The q query parameter accepts web search syntax. Results are ranked by relevance, carry excerpts with
+// the matched words highlighted and are paginated with the limit and offset query parameters.
+func (ih *ImageHandler) Search(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseSearchOptions(r.URL.Query())
 	if err != nil {
-		log.Errorf("Failed to get all images: %v", err)
-		http.Error(w, "Failed to get all images", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	pageSize := opts.Limit
+	opts.Limit++
+	results, err := ih.imageService.Search(r.Context(), opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := searchResponse{Results: make([]searchResultItem, 0, len(results))}
+	if len(results) > pageSize {
+		results = results[:pageSize]
+		response.NextOffset = opts.Offset + pageSize
+
+		query := r.URL.Query()
+		query.Set("offset", strconv.Itoa(response.NextOffset))
+		response.Links.Next = r.URL.Path + "?" + query.Encode()
+	}
+	for _, result := range results {
+		response.Results = append(response.Results, searchResultItem{
+			SearchResult: result,
+			Links:        newImageLinks(result.ImageMetadata, opts.Source),
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(images); err != nil {
-		log.Errorf("Failed to encode response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	encodeJSON(w, r, response)
+}
+
+// joinQuery joins the non-empty encoded query strings.
+func joinQuery(queries ...string) string {
+	joined := ""
+	for _, query := range queries {
+		if query == "" {
+			continue
+		}
+		if joined != "" {
+			joined += "&"
+		}
+		joined += query
+	}
+	return joined
+}
+
+// prefixQuery prefixes a non-empty encoded query string with a question mark.
+func prefixQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+	return "?" + query
+}
+
+// GetRaw handles the HTTP request for retrieving the raw bytes of an image of a source by date.
+// The date accepts the same aliases as GetByDate.
+// The size query parameter selects the thumb, medium, original (default) or hd rendition.
+// The Content-Type is sniffed from the stored data, and ETag, Last-Modified and Range
+// requests are supported so that browsers and CDNs can cache the response. Requests for a date alias
+// are redirected to the URL of the date it resolves to, which is not cached, so that only responses
+// for a concrete date, whose picture never changes, are cached.
+func (ih *ImageHandler) GetRaw(w http.ResponseWriter, r *http.Request) {
+	source := sourceParam(r.URL.Query())
+	value := r.PathValue("date")
+	date, err := ih.resolveDate(r.Context(), source, value)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if date != value {
+		location := *r.URL
+		location.Path = strings.TrimSuffix(location.Path, value+"/raw") + date + "/raw"
+		location.RawPath = ""
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, location.RequestURI(), http.StatusFound)
+		return
+	}
+	modTime, err := time.Parse(dateLayout, date)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("invalid resolved date %q: %w", date, err))
+		return
+	}
+
+	// checksum is the stored checksum of the data, if known; it is computed from the data otherwise.
+	var data []byte
+	var checksum string
+	switch size := r.URL.Query().Get("size"); size {
+	case "", "original":
+		var image *model.Image
+		image, err = ih.imageService.GetByDate(r.Context(), source, date)
+		if err == nil && len(image.Data) == 0 {
+			err = service.NotFound("no picture is stored for the %s %s of date %s", source, image.MediaType, date)
+		}
+		if err == nil {
+			data, checksum = image.Data, image.Checksum
+		}
+	case model.RenditionThumb, model.RenditionMedium:
+		var rendition *model.Rendition
+		rendition, err = ih.imageService.GetRendition(r.Context(), source, date, size)
+		if err == nil {
+			data = rendition.Data
+		}
+	case "hd":
+		data, err = ih.imageService.GetHDDataByDate(r.Context(), source, date)
+	default:
+		err = service.InvalidInput("size must be one of %s, %s, original and hd", model.RenditionThumb, model.RenditionMedium)
+	}
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+
+	if checksum == "" {
+		checksum = imaging.Checksum(data)
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("ETag", `"`+checksum+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
+
+// encodeJSON writes v as the JSON response body. Encoding is traced in its own span, so the time it takes
+// can be told apart from the time spent retrieving the response.
+func encodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	_, span := tracing.Start(r.Context(), "encode response")
+	defer span.End()
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.FromContext(r.Context()).Errorf("Failed to encode response: %v", err)
+	}
 }
@@ -1,49 +1,104 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
 )
 
 type mockImageService struct {
-	GetByDateFunc func(date string) (*model.Image, error)
-	GetAllFunc    func() ([]*model.Image, error)
-	SaveFunc      func(image *model.Image) error
+	GetByDateFunc       func(source, date string) (*model.Image, error)
+	GetHDDataByDateFunc func(source, date string) ([]byte, error)
+	GetAllFunc          func() ([]*model.Image, error)
+	SaveFunc            func(image *model.Image) error
+	GetDatesFunc        func(source, from, to string) ([]string, error)
+	GetRandomDateFunc   func(source string) (string, error)
+	ListFunc            func(opts model.ListOptions) ([]*model.ImageMetadata, error)
+	SearchFunc          func(opts model.SearchOptions) ([]*model.SearchResult, error)
+	MigrateBlobsFunc    func(batchSize int) (int, error)
+
+	GetRenditionFunc       func(source, date, name string) (*model.Rendition, error)
+	GenerateRenditionsFunc func(batchSize int) (int, error)
+	GetStatsFunc           func() (*model.StorageStats, error)
+}
+
+func (m *mockImageService) GetByDate(ctx context.Context, source, date string) (*model.Image, error) {
+	return m.GetByDateFunc(source, date)
 }
 
-func (m *mockImageService) GetByDate(date string) (*model.Image, error) {
-	return m.GetByDateFunc(date)
+func (m *mockImageService) GetHDDataByDate(ctx context.Context, source, date string) ([]byte, error) {
+	return m.GetHDDataByDateFunc(source, date)
 }
 
-func (m *mockImageService) GetAll() ([]*model.Image, error) {
+func (m *mockImageService) GetAll(ctx context.Context) ([]*model.Image, error) {
 	return m.GetAllFunc()
 }
-func (m *mockImageService) Save(image *model.Image) error {
+func (m *mockImageService) Save(ctx context.Context, image *model.Image) error {
 	return m.SaveFunc(image)
 }
 
+func (m *mockImageService) GetDates(ctx context.Context, source, from, to string) ([]string, error) {
+	return m.GetDatesFunc(source, from, to)
+}
+
+func (m *mockImageService) GetRandomDate(ctx context.Context, source string) (string, error) {
+	return m.GetRandomDateFunc(source)
+}
+
+func (m *mockImageService) List(ctx context.Context, opts model.ListOptions) ([]*model.ImageMetadata, error) {
+	return m.ListFunc(opts)
+}
+
+func (m *mockImageService) Search(ctx context.Context, opts model.SearchOptions) ([]*model.SearchResult, error) {
+	return m.SearchFunc(opts)
+}
+
+func (m *mockImageService) MigrateBlobs(ctx context.Context, batchSize int) (int, error) {
+	return m.MigrateBlobsFunc(batchSize)
+}
+
+func (m *mockImageService) GetRendition(ctx context.Context, source, date, name string) (*model.Rendition, error) {
+	return m.GetRenditionFunc(source, date, name)
+}
+
+func (m *mockImageService) GenerateRenditions(ctx context.Context, batchSize int) (int, error) {
+	return m.GenerateRenditionsFunc(batchSize)
+}
+
+func (m *mockImageService) GetStats(ctx context.Context) (*model.StorageStats, error) {
+	return m.GetStatsFunc()
+}
+
 func TestImageHandler_GetByDate(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
 		name               string
 		date               string
-		getByDateFunc      func(date string) (*model.Image, error)
+		getByDateFunc      func(source, date string) (*model.Image, error)
+		getRandomDateFunc  func(source string) (string, error)
 		expectedStatusCode int
+		expectedErrorCode  string
 		expectedResponse   *model.Image
 	}{
 		{
 			name: "Success",
 			date: "2024-05-18",
-			getByDateFunc: func(date string) (*model.Image, error) {
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				require.Equal(t, model.DefaultSource, source)
 				return &model.Image{
 					ID:          uuid.New(),
 					Date:        "2024-05-18",
@@ -64,32 +119,92 @@ func TestImageHandler_GetByDate(t *testing.T) {
 		{
 			name: "ImageNotFound",
 			date: "2024-05-19",
-			getByDateFunc: func(date string) (*model.Image, error) {
-				return nil, nil
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return nil, service.NotFound("no %s image for date %s", source, date)
 			},
 			expectedStatusCode: http.StatusNotFound,
+			expectedErrorCode:  "not_found",
 		},
 		{
 			name: "ServiceError",
 			date: "2024-05-20",
-			getByDateFunc: func(date string) (*model.Image, error) {
+			getByDateFunc: func(source, date string) (*model.Image, error) {
 				return nil, errors.New("service error")
 			},
 			expectedStatusCode: http.StatusInternalServerError,
+			expectedErrorCode:  "internal",
+		},
+		{
+			name: "BlobStoreUnavailable",
+			date: "2024-05-20",
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return nil, service.Unavailable(errors.New("connection refused"), "blob store is unavailable")
+			},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedErrorCode:  "upstream_unavailable",
+		},
+		{
+			name: "Yesterday",
+			date: "yesterday",
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				require.Equal(t, "2024-05-19", date)
+				return &model.Image{Date: date}, nil
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   &model.Image{Date: "2024-05-19"},
+		},
+		{
+			name: "Random",
+			date: "random",
+			getRandomDateFunc: func(source string) (string, error) {
+				require.Equal(t, model.DefaultSource, source)
+				return "2001-01-01", nil
+			},
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return &model.Image{Date: date}, nil
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   &model.Image{Date: "2001-01-01"},
+		},
+		{
+			name: "RandomWithoutImages",
+			date: "random",
+			getRandomDateFunc: func(source string) (string, error) {
+				return "", service.NotFound("no %s images are stored", source)
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedErrorCode:  "not_found",
 		},
 		{
 			name:               "BadRequest",
 			date:               "",
 			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorCode:  "invalid_input",
+		},
+		{
+			name:               "MalformedDate",
+			date:               "2024-5-1",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorCode:  "invalid_input",
+		},
+		{
+			name:               "FutureDate",
+			date:               "2024-05-21",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorCode:  "invalid_input",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			imageService := &mockImageService{
-				GetByDateFunc: tt.getByDateFunc,
+				GetByDateFunc:     tt.getByDateFunc,
+				GetRandomDateFunc: tt.getRandomDateFunc,
 			}
 			imageHandler := NewImageHandler(imageService)
+			imageHandler.now = func() time.Time {
+				return time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+			}
 
 			req, err := http.NewRequest(http.MethodGet, "/images/date?date="+tt.date, nil)
 			require.NoError(t, err)
@@ -99,6 +214,16 @@ func TestImageHandler_GetByDate(t *testing.T) {
 
 			require.Equal(t, tt.expectedStatusCode, recorder.Code)
 
+			if tt.expectedErrorCode != "" {
+				var response errorResponse
+				require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+				require.Equal(t, tt.expectedErrorCode, response.Error.Code)
+				require.NotEmpty(t, response.Error.Message)
+				if tt.expectedStatusCode == http.StatusInternalServerError {
+					require.NotContains(t, response.Error.Message, "service error")
+				}
+			}
+
 			if tt.expectedResponse != nil {
 				var image model.Image
 				err = json.NewDecoder(recorder.Body).Decode(&image)
@@ -112,56 +237,156 @@ func TestImageHandler_GetByDate(t *testing.T) {
 	}
 }
 
+func TestParseDate(t *testing.T) {
+	t.Parallel()
+
+	// 03:00 UTC is still the previous day in US Eastern, where APOD days start.
+	now := time.Date(2024, 5, 20, 3, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected string
+		wantErr  string
+	}{
+		{value: "2024-05-18", expected: "2024-05-18"},
+		{value: "1995-06-16", expected: "1995-06-16"},
+		{value: "today", expected: "2024-05-19"},
+		{value: "yesterday", expected: "2024-05-18"},
+		{value: "", wantErr: "date is required"},
+		{value: "2024-5-1", wantErr: "YYYY-MM-DD format"},
+		{value: "2024-02-30", wantErr: "YYYY-MM-DD format"},
+		{value: " 2024-05-18", wantErr: "YYYY-MM-DD format"},
+		{value: "'; DROP TABLE images; --", wantErr: "YYYY-MM-DD format"},
+		{value: "Today", wantErr: "YYYY-MM-DD format"},
+		{value: "1995-06-15", wantErr: "before the first APOD on 1995-06-16"},
+		{value: "2024-05-20", wantErr: "in the future"},
+	}
+
+	for _, tt := range tests {
+		date, err := parseDate(tt.value, now)
+		if tt.wantErr != "" {
+			require.ErrorContains(t, err, tt.wantErr, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		require.Equal(t, tt.expected, date, tt.value)
+	}
+}
+
 func TestImageHandler_GetAll(t *testing.T) {
 	t.Parallel()
 
+	listFunc := func(opts model.ListOptions) ([]*model.ImageMetadata, error) {
+		var images []*model.ImageMetadata
+		for _, date := range []string{"2024-05-18", "2024-05-19", "2024-05-20"} {
+			if opts.After != "" && date <= opts.After {
+				continue
+			}
+			images = append(images, &model.ImageMetadata{
+				ID:          uuid.New(),
+				Date:        date,
+				Title:       "A Beautiful Nebula",
+				Explanation: "This is an explanation of the beautiful nebula.",
+				MediaType:   "image",
+				Size:        4,
+			})
+		}
+		if len(images) > opts.Limit {
+			images = images[:opts.Limit]
+		}
+		return images, nil
+	}
+
 	tests := []struct {
 		name               string
-		getAllFunc         func() ([]*model.Image, error)
+		query              string
+		listFunc           func(opts model.ListOptions) ([]*model.ImageMetadata, error)
 		expectedStatusCode int
-		expectedResponse   []*model.Image
+		expectedDates      []string
+		expectedCursor     string
 	}{
 		{
-			name: "Success",
-			getAllFunc: func() ([]*model.Image, error) {
-				return []*model.Image{
-					{
-						ID:          uuid.New(),
-						Date:        "2024-05-18",
-						Title:       "A Beautiful Nebula",
-						Explanation: "This is an explanation of the beautiful nebula.",
-						MediaType:   "image",
-						Data:        []byte{0x89, 0x50, 0x4E, 0x47},
-					},
-					{
-						ID:          uuid.New(),
-						Date:        "2024-05-19",
-						Title:       "Another Beautiful Nebula",
-						Explanation: "This is an explanation of another beautiful nebula.",
-						MediaType:   "image",
-						Data:        []byte{0x89, 0x50, 0x4E, 0x47},
-					},
-				}, nil
+			name:               "Success",
+			listFunc:           listFunc,
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-18", "2024-05-19", "2024-05-20"},
+		},
+		{
+			name:               "FirstPage",
+			query:              "limit=2",
+			listFunc:           listFunc,
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-18", "2024-05-19"},
+			expectedCursor:     "2024-05-19",
+		},
+		{
+			name:               "LastPage",
+			query:              "limit=2&after=2024-05-19",
+			listFunc:           listFunc,
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-20"},
+		},
+		{
+			name:  "Filters",
+			query: "from=2024-05-01&to=2024-05-31&order=desc",
+			listFunc: func(opts model.ListOptions) ([]*model.ImageMetadata, error) {
+				require.Equal(t, model.ListOptions{
+					Source: model.DefaultSource,
+					Limit:  defaultListLimit + 1,
+					From:   "2024-05-01",
+					To:     "2024-05-31",
+					Order:  model.SortDesc,
+				}, opts)
+				return nil, nil
 			},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse: []*model.Image{
-				{
-					Date:        "2024-05-18",
-					Title:       "A Beautiful Nebula",
-					Explanation: "This is an explanation of the beautiful nebula.",
-					MediaType:   "image",
-				},
-				{
-					Date:        "2024-05-19",
-					Title:       "Another Beautiful Nebula",
-					Explanation: "This is an explanation of another beautiful nebula.",
-					MediaType:   "image",
-				},
+			expectedDates:      []string{},
+		},
+		{
+			name:               "InvalidLimit",
+			query:              "limit=1000",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "InvalidDate",
+			query:              "from=2024-5-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:  "DimensionFilters",
+			query: "orientation=portrait&min_width=1080&min_height=1920",
+			listFunc: func(opts model.ListOptions) ([]*model.ImageMetadata, error) {
+				require.Equal(t, model.ListOptions{
+					Source:      model.DefaultSource,
+					Limit:       defaultListLimit + 1,
+					Order:       model.SortAsc,
+					Orientation: model.OrientationPortrait,
+					MinWidth:    1080,
+					MinHeight:   1920,
+				}, opts)
+				return nil, nil
 			},
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{},
+		},
+		{
+			name:               "InvalidOrder",
+			query:              "order=random",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "InvalidOrientation",
+			query:              "orientation=diagonal",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "InvalidMinWidth",
+			query:              "min_width=0",
+			expectedStatusCode: http.StatusBadRequest,
 		},
 		{
 			name: "ServiceError",
-			getAllFunc: func() ([]*model.Image, error) {
+			listFunc: func(opts model.ListOptions) ([]*model.ImageMetadata, error) {
 				return nil, errors.New("service error")
 			},
 			expectedStatusCode: http.StatusInternalServerError,
@@ -171,11 +396,11 @@ func TestImageHandler_GetAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			imageService := &mockImageService{
-				GetAllFunc: tt.getAllFunc,
+				ListFunc: tt.listFunc,
 			}
 			imageHandler := NewImageHandler(imageService)
 
-			req, err := http.NewRequest(http.MethodGet, "/images/all", nil)
+			req, err := http.NewRequest(http.MethodGet, "/images?"+tt.query, nil)
 			require.NoError(t, err)
 
 			recorder := httptest.NewRecorder()
@@ -183,18 +408,418 @@ func TestImageHandler_GetAll(t *testing.T) {
 
 			require.Equal(t, tt.expectedStatusCode, recorder.Code)
 
-			if tt.expectedResponse != nil {
-				var images []*model.Image
-				err = json.NewDecoder(recorder.Body).Decode(&images)
+			if tt.expectedDates != nil {
+				var response imageListResponse
+				err = json.NewDecoder(recorder.Body).Decode(&response)
 				require.NoError(t, err)
-				require.Equal(t, len(tt.expectedResponse), len(images))
-				for i, image := range images {
-					require.Equal(t, tt.expectedResponse[i].Date, image.Date)
-					require.Equal(t, tt.expectedResponse[i].Title, image.Title)
-					require.Equal(t, tt.expectedResponse[i].Explanation, image.Explanation)
-					require.Equal(t, tt.expectedResponse[i].MediaType, image.MediaType)
+
+				dates := []string{}
+				for _, image := range response.Images {
+					dates = append(dates, image.Date)
+					require.Equal(t, "/images/"+image.Date+"/raw", image.Links.Raw)
+				}
+				require.Equal(t, tt.expectedDates, dates)
+				require.Equal(t, tt.expectedCursor, response.NextCursor)
+				if tt.expectedCursor != "" {
+					require.Contains(t, response.Links.Next, "after="+tt.expectedCursor)
 				}
 			}
 		})
 	}
 }
+
+func TestImageHandler_Search(t *testing.T) {
+	t.Parallel()
+
+	searchFunc := func(opts model.SearchOptions) ([]*model.SearchResult, error) {
+		var results []*model.SearchResult
+		for i, date := range []string{"2024-05-20", "2024-05-18", "2024-05-17"} {
+			results = append(results, &model.SearchResult{
+				ImageMetadata: &model.ImageMetadata{Source: opts.Source, Date: date, Title: "Andromeda", MediaType: "image", Size: 4},
+				Rank:          1 / float64(i+1),
+				Highlight:     model.Highlight{Title: "<mark>Andromeda</mark>"},
+			})
+		}
+		results = results[min(opts.Offset, len(results)):]
+		return results[:min(opts.Limit, len(results))], nil
+	}
+
+	tests := []struct {
+		name               string
+		query              string
+		searchFunc         func(opts model.SearchOptions) ([]*model.SearchResult, error)
+		expectedStatusCode int
+		expectedDates      []string
+		expectedNextOffset int
+		expectedNextLink   string
+	}{
+		{
+			name:               "Success",
+			query:              "q=andromeda",
+			searchFunc:         searchFunc,
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-20", "2024-05-18", "2024-05-17"},
+		},
+		{
+			name:               "FirstPage",
+			query:              "q=andromeda&limit=2",
+			searchFunc:         searchFunc,
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-20", "2024-05-18"},
+			expectedNextOffset: 2,
+			expectedNextLink:   "/images/search?limit=2&offset=2&q=andromeda",
+		},
+		{
+			name:               "LastPage",
+			query:              "q=andromeda&limit=2&offset=2",
+			searchFunc:         searchFunc,
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{"2024-05-17"},
+		},
+		{
+			name:  "NoResults",
+			query: "q=nebula",
+			searchFunc: func(opts model.SearchOptions) ([]*model.SearchResult, error) {
+				return nil, nil
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedDates:      []string{},
+		},
+		{
+			name:               "MissingQuery",
+			query:              "q=+",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "InvalidLimit",
+			query:              "q=andromeda&limit=101",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "InvalidOffset",
+			query:              "q=andromeda&offset=-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:  "ServiceError",
+			query: "q=andromeda",
+			searchFunc: func(opts model.SearchOptions) ([]*model.SearchResult, error) {
+				return nil, errors.New("service error")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageHandler := NewImageHandler(&mockImageService{SearchFunc: tt.searchFunc})
+
+			req, err := http.NewRequest(http.MethodGet, "/images/search?"+tt.query, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			imageHandler.Search(recorder, req)
+
+			require.Equal(t, tt.expectedStatusCode, recorder.Code)
+			if tt.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Results []struct {
+					Date      string          `json:"date"`
+					Rank      float64         `json:"rank"`
+					Highlight model.Highlight `json:"highlight"`
+					Links     imageLinks      `json:"links"`
+				} `json:"results"`
+				NextOffset int       `json:"next_offset"`
+				Links      listLinks `json:"links"`
+			}
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+
+			dates := []string{}
+			for _, result := range response.Results {
+				dates = append(dates, result.Date)
+				require.Equal(t, "<mark>Andromeda</mark>", result.Highlight.Title)
+				require.Equal(t, "/images/date?date="+result.Date, result.Links.Self)
+				require.NotZero(t, result.Rank)
+			}
+			require.Equal(t, tt.expectedDates, dates)
+			require.Equal(t, tt.expectedNextOffset, response.NextOffset)
+			require.Equal(t, tt.expectedNextLink, response.Links.Next)
+		})
+	}
+}
+
+func TestImageHandler_GetAllSource(t *testing.T) {
+	t.Parallel()
+
+	imageService := &mockImageService{
+		ListFunc: func(opts model.ListOptions) ([]*model.ImageMetadata, error) {
+			require.Equal(t, "drop", opts.Source)
+			return []*model.ImageMetadata{
+				{ID: uuid.New(), Source: "drop", Date: "2024-05-18", MediaType: "image", Size: 4, HDSize: 8},
+			}, nil
+		},
+	}
+	imageHandler := NewImageHandler(imageService)
+
+	req, err := http.NewRequest(http.MethodGet, "/images?source=drop", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	imageHandler.GetAll(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response imageListResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	require.Len(t, response.Images, 1)
+	require.Equal(t, "drop", response.Images[0].Source)
+	require.Equal(t, imageLinks{
+		Self:  "/images/date?date=2024-05-18&source=drop",
+		Raw:   "/images/2024-05-18/raw?source=drop",
+		RawHD: "/images/2024-05-18/raw?size=hd&source=drop",
+	}, response.Images[0].Links)
+}
+
+func TestImageHandler_GetRaw(t *testing.T) {
+	t.Parallel()
+
+	pngData := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	gifData := []byte("GIF89a\x01\x00\x01\x00")
+	etag := func(data []byte) string {
+		checksum := sha256.Sum256(data)
+		return `"` + hex.EncodeToString(checksum[:]) + `"`
+	}
+
+	tests := []struct {
+		name                string
+		date                string
+		size                string
+		headers             map[string]string
+		getByDateFunc       func(source, date string) (*model.Image, error)
+		getHDDataFunc       func(source, date string) ([]byte, error)
+		getRenditionFunc    func(source, date, name string) (*model.Rendition, error)
+		expectedStatusCode  int
+		expectedContentType string
+		expectedBody        []byte
+	}{
+		{
+			name: "PNG",
+			date: "2024-05-18",
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return &model.Image{Date: date, Data: pngData}, nil
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/png",
+			expectedBody:        pngData,
+		},
+		{
+			name: "GIF",
+			date: "2024-05-18",
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return &model.Image{Date: date, Data: gifData}, nil
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/gif",
+			expectedBody:        gifData,
+		},
+		{
+			name: "HD",
+			date: "2024-05-18",
+			size: "hd",
+			getHDDataFunc: func(source, date string) ([]byte, error) {
+				return gifData, nil
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/gif",
+			expectedBody:        gifData,
+		},
+		{
+			name: "Thumb",
+			date: "2024-05-18",
+			size: "thumb",
+			getRenditionFunc: func(source, date, name string) (*model.Rendition, error) {
+				require.Equal(t, model.RenditionThumb, name)
+				return &model.Rendition{Name: name, ContentType: "image/png", Data: pngData}, nil
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/png",
+			expectedBody:        pngData,
+		},
+		{
+			name: "Medium",
+			date: "2024-05-18",
+			size: "medium",
+			getRenditionFunc: func(source, date, name string) (*model.Rendition, error) {
+				require.Equal(t, model.RenditionMedium, name)
+				return &model.Rendition{Name: name, ContentType: "image/gif", Data: gifData}, nil
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/gif",
+			expectedBody:        gifData,
+		},
+		{
+			name: "RenditionNotFound",
+			date: "2024-05-18",
+			size: "thumb",
+			getRenditionFunc: func(source, date, name string) (*model.Rendition, error) {
+				return nil, service.NotFound("no %s rendition of the %s image for date %s", name, source, date)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "HDNotFound",
+			date: "2024-05-18",
+			size: "hd",
+			getHDDataFunc: func(source, date string) ([]byte, error) {
+				return nil, service.NotFound("no HD rendition of the %s image for date %s", source, date)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "InvalidSize",
+			date:               "2024-05-18",
+			size:               "huge",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Range",
+			date:    "2024-05-18",
+			headers: map[string]string{"Range": "bytes=0-3"},
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return &model.Image{Date: date, Data: pngData}, nil
+			},
+			expectedStatusCode:  http.StatusPartialContent,
+			expectedContentType: "image/png",
+			expectedBody:        pngData[:4],
+		},
+		{
+			name:    "NotModifiedByETag",
+			date:    "2024-05-18",
+			headers: map[string]string{"If-None-Match": etag(pngData)},
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return &model.Image{Date: date, Data: pngData}, nil
+			},
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:    "NotModifiedByStoredChecksum",
+			date:    "2024-05-18",
+			headers: map[string]string{"If-None-Match": `"` + strings.Repeat("a", 64) + `"`},
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				image := &model.Image{Date: date, Data: pngData}
+				image.Checksum = strings.Repeat("a", 64)
+				return image, nil
+			},
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:    "NotModifiedSince",
+			date:    "2024-05-18",
+			headers: map[string]string{"If-Modified-Since": "Sun, 19 May 2024 00:00:00 GMT"},
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return &model.Image{Date: date, Data: pngData}, nil
+			},
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name: "ImageNotFound",
+			date: "2024-05-19",
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return nil, service.NotFound("no %s image for date %s", source, date)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "VideoWithoutThumbnail",
+			date: "2024-05-19",
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return &model.Image{Date: date, MediaType: "video", Video: &model.Video{Provider: "vimeo"}}, nil
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "ServiceError",
+			date: "2024-05-20",
+			getByDateFunc: func(source, date string) (*model.Image, error) {
+				return nil, errors.New("service error")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "BadRequest",
+			date:               "2024-5-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageService := &mockImageService{
+				GetByDateFunc:       tt.getByDateFunc,
+				GetHDDataByDateFunc: tt.getHDDataFunc,
+				GetRenditionFunc:    tt.getRenditionFunc,
+			}
+			imageHandler := NewImageHandler(imageService)
+
+			req, err := http.NewRequest(http.MethodGet, "/images/"+tt.date+"/raw?size="+tt.size, nil)
+			require.NoError(t, err)
+			req.SetPathValue("date", tt.date)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			imageHandler.GetRaw(recorder, req)
+
+			require.Equal(t, tt.expectedStatusCode, recorder.Code)
+
+			if tt.expectedBody != nil {
+				require.Equal(t, tt.expectedContentType, recorder.Header().Get("Content-Type"))
+				require.Equal(t, tt.expectedBody, recorder.Body.Bytes())
+				require.NotEmpty(t, recorder.Header().Get("ETag"))
+				require.Equal(t, "Sat, 18 May 2024 00:00:00 GMT", recorder.Header().Get("Last-Modified"))
+			}
+		})
+	}
+}
+
+func TestImageHandler_GetRawAlias(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		date             string
+		expectedLocation string
+	}{
+		{name: "Today", date: "today", expectedLocation: "/images/2024-05-19/raw?size=thumb&source=apod"},
+		{name: "Yesterday", date: "yesterday", expectedLocation: "/images/2024-05-18/raw?size=thumb&source=apod"},
+		{name: "Random", date: "random", expectedLocation: "/images/2001-02-03/raw?size=thumb&source=apod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			imageService := &mockImageService{
+				GetRandomDateFunc: func(source string) (string, error) {
+					return "2001-02-03", nil
+				},
+			}
+			imageHandler := NewImageHandler(imageService)
+			imageHandler.now = func() time.Time { return time.Date(2024, 5, 19, 12, 0, 0, 0, time.UTC) }
+
+			req := httptest.NewRequest(http.MethodGet, "/images/"+tt.date+"/raw?size=thumb&source=apod", nil)
+			req.SetPathValue("date", tt.date)
+			recorder := httptest.NewRecorder()
+			imageHandler.GetRaw(recorder, req)
+
+			require.Equal(t, http.StatusFound, recorder.Code)
+			require.Equal(t, tt.expectedLocation, recorder.Header().Get("Location"))
+			require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+			require.Empty(t, recorder.Header().Get("ETag"))
+		})
+	}
+}
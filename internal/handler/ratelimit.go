@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/metrics"
+	"github.com/EgMeln/YoungAstrologer/internal/ratelimit"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+// anyRoute keys the buckets of the limit applied to every request of an IP address, whatever its route.
+const anyRoute = "*"
+
+// RateLimit throttles the requests of every client with a token bucket per client and route. Clients are
+// identified by their API key if the request was authenticated and by their IP address otherwise.
+type RateLimit struct {
+	limiter           ratelimit.Limiter
+	defaultLimit      ratelimit.Limit
+	limits            map[string]ratelimit.Limit
+	ipLimit           ratelimit.Limit
+	trustForwardedFor bool
+}
+
+// NewRateLimit creates a new RateLimit instance. limits overrides defaultLimit by route pattern, and ipLimit
+// bounds the requests of every IP address before they are authenticated. If trustForwardedFor is set,
+// the IP address of a client is taken from the X-Forwarded-For header, which must then be set by a proxy
+// in front of the service.
+func NewRateLimit(limiter ratelimit.Limiter, defaultLimit ratelimit.Limit, limits map[string]ratelimit.Limit,
+	ipLimit ratelimit.Limit, trustForwardedFor bool) *RateLimit {
+	return &RateLimit{
+		limiter:           limiter,
+		defaultLimit:      defaultLimit,
+		limits:            limits,
+		ipLimit:           ipLimit,
+		trustForwardedFor: trustForwardedFor,
+	}
+}
+
+// Limit wraps next, which is served at the route pattern, so that it is served only while the client of
+// the request has tokens left. Every response carries the RateLimit-Limit (the capacity of the bucket),
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy (the requests allowed per window and the burst)
+// headers; throttled requests are answered with 429 and a Retry-After header. Requests are served if the limiter fails, so an outage of its store does not
+// take the API down.
+func (rl *RateLimit) Limit(route string, next http.Handler) http.Handler {
+	limit, ok := rl.limits[route]
+	if !ok {
+		limit = rl.defaultLimit
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.allow(w, r, route, route+" "+rl.client(r), limit) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// LimitIP wraps next like Limit, except that the requests are counted per IP address across every route.
+// It is meant to be applied before authentication, so that requests with a missing or invalid API key,
+// each of which costs a lookup, are throttled too.
+func (rl *RateLimit) LimitIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.allow(w, r, anyRoute, anyRoute+" "+rl.clientIP(r), rl.ipLimit) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allow takes a token from the bucket of the key and sets the rate limit headers. If the bucket is empty,
+// it writes the error response and returns false.
+func (rl *RateLimit) allow(w http.ResponseWriter, r *http.Request, route, key string, limit ratelimit.Limit) bool {
+	result, err := rl.limiter.Allow(r.Context(), key, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Warnf("Error checking rate limit, serving the request: %v", err)
+		return true
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Quota, seconds(limit.Window), limit.Burst))
+	if !result.Allowed {
+		retryAfter := max(seconds(result.RetryAfter), 1)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		metrics.HTTPRateLimited.WithLabelValues(route).Inc()
+		writeError(w, r, service.RateLimited("rate limit exceeded, retry in %d seconds", retryAfter))
+		return false
+	}
+	return true
+}
+
+// client returns the identity the requests of a client are counted under.
+func (rl *RateLimit) client(r *http.Request) string {
+	if key := APIKeyFromContext(r.Context()); key != nil {
+		return "key:" + key.ID.String()
+	}
+	return rl.clientIP(r)
+}
+
+// clientIP returns the identity of the IP address of the client.
+func (rl *RateLimit) clientIP(r *http.Request) string {
+	if rl.trustForwardedFor {
+		// The last address is the one the proxy in front of the service appended; any before it were
+		// provided by the client.
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			addresses := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(addresses[len(addresses)-1]); ip != "" {
+				return "ip:" + ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// seconds rounds d up to whole seconds, as the rate limit headers express durations.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
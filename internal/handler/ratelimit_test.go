@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/ratelimit"
+)
+
+type mockLimiter struct {
+	AllowFunc func(key string, limit ratelimit.Limit) (ratelimit.Result, error)
+}
+
+func (m *mockLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return m.AllowFunc(key, limit)
+}
+
+func TestRateLimit_Limit(t *testing.T) {
+	t.Parallel()
+
+	defaultLimit := ratelimit.PerPeriod(60, time.Minute, 10)
+	rawLimit := ratelimit.PerPeriod(1, time.Minute, 2)
+	apiKey := &model.APIKey{ID: uuid.New(), Role: model.RoleRead}
+
+	tests := []struct {
+		name              string
+		route             string
+		trustForwardedFor bool
+		remoteAddr        string
+		forwardedFor      string
+		apiKey            *model.APIKey
+		expectedKey       string
+		expectedLimit     ratelimit.Limit
+	}{
+		{
+			name:          "RemoteAddr",
+			route:         "/images",
+			remoteAddr:    "203.0.113.7:52311",
+			forwardedFor:  "198.51.100.1",
+			expectedKey:   "/images ip:203.0.113.7",
+			expectedLimit: defaultLimit,
+		},
+		{
+			name:              "ForwardedFor",
+			route:             "/images",
+			trustForwardedFor: true,
+			remoteAddr:        "10.0.0.2:52311",
+			forwardedFor:      "192.0.2.1, 198.51.100.1",
+			expectedKey:       "/images ip:198.51.100.1",
+			expectedLimit:     defaultLimit,
+		},
+		{
+			name:              "NoForwardedFor",
+			route:             "/images",
+			trustForwardedFor: true,
+			remoteAddr:        "[2001:db8::1]:52311",
+			expectedKey:       "/images ip:2001:db8::1",
+			expectedLimit:     defaultLimit,
+		},
+		{
+			name:          "APIKey",
+			route:         "GET /images/{date}/raw",
+			remoteAddr:    "203.0.113.7:52311",
+			apiKey:        apiKey,
+			expectedKey:   "GET /images/{date}/raw key:" + apiKey.ID.String(),
+			expectedLimit: rawLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			limiter := &mockLimiter{
+				AllowFunc: func(key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+					require.Equal(t, tt.expectedKey, key)
+					require.Equal(t, tt.expectedLimit, limit)
+					return ratelimit.Result{Allowed: true, Limit: limit.Burst, Remaining: limit.Burst - 1, Reset: 1500 * time.Millisecond}, nil
+				},
+			}
+			rateLimit := NewRateLimit(limiter, defaultLimit, map[string]ratelimit.Limit{"GET /images/{date}/raw": rawLimit}, defaultLimit, tt.trustForwardedFor)
+			handler := rateLimit.Limit(tt.route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, "/images", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.apiKey != nil {
+				req = req.WithContext(context.WithValue(req.Context(), apiKeyKey{}, tt.apiKey))
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, "2", recorder.Header().Get("RateLimit-Reset"))
+			require.Equal(t, strconv.Itoa(tt.expectedLimit.Burst), recorder.Header().Get("RateLimit-Limit"))
+			require.Empty(t, recorder.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestRateLimit_Throttled(t *testing.T) {
+	t.Parallel()
+
+	limit := ratelimit.PerPeriod(1, time.Minute, 2)
+	rateLimit := NewRateLimit(ratelimit.NewMemoryLimiter(), limit, nil, limit, false)
+	served := 0
+	handler := rateLimit.Limit("/images", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/images", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for i := 0; i < 2; i++ {
+		recorder := serve("203.0.113.7:52311")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+		require.Equal(t, "1;w=60;burst=2", recorder.Header().Get("RateLimit-Policy"))
+		require.Equal(t, []string{"1", "0"}[i], recorder.Header().Get("RateLimit-Remaining"))
+	}
+
+	recorder := serve("203.0.113.7:40000")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	require.Equal(t, "120", recorder.Header().Get("RateLimit-Reset"))
+	var response errorResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	require.Equal(t, codeRateLimited, response.Error.Code)
+
+	require.Equal(t, http.StatusOK, serve("198.51.100.1:52311").Code)
+	require.Equal(t, 3, served)
+}
+
+func TestRateLimit_LimiterError(t *testing.T) {
+	t.Parallel()
+
+	limiter := &mockLimiter{
+		AllowFunc: func(key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+			return ratelimit.Result{}, errors.New("connection refused")
+		},
+	}
+	served := false
+	limit := ratelimit.PerPeriod(1, time.Minute, 1)
+	handler := NewRateLimit(limiter, limit, nil, limit, false).
+		Limit("/images", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = true
+		}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/images", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, served)
+	require.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimit_LimitIP(t *testing.T) {
+	t.Parallel()
+
+	limit := ratelimit.PerPeriod(60, time.Minute, 10)
+	ipLimit := ratelimit.PerPeriod(2, time.Minute, 2)
+	rateLimit := NewRateLimit(ratelimit.NewMemoryLimiter(), limit, nil, ipLimit, false)
+
+	// Requests rejected by the wrapped handler, such as those with an invalid API key, are counted too.
+	authenticated := 0
+	handler := rateLimit.LimitIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticated++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	codes := make([]int, 0, 3)
+	for _, path := range []string{"/images", "/admin/jobs", "/images/search"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.7:52311"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		codes = append(codes, recorder.Code)
+	}
+
+	require.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+	require.Equal(t, 2, authenticated)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/metrics"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/provider"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+	"github.com/EgMeln/YoungAstrologer/internal/tracing"
+)
+
+// SourceHandler fetches daily pictures from an upstream source and saves them.
+type SourceHandler struct {
+	imageService service.ImageService
+	provider     provider.Provider
+	downloadHD   bool
+}
+
+// NewSourceHandler creates a new instance of SourceHandler saving the records of the provider with imageService.
+// If downloadHD is set, the high definition rendition of each image is downloaded and stored as well.
+func NewSourceHandler(imageService service.ImageService, provider provider.Provider, downloadHD bool) *SourceHandler {
+	return &SourceHandler{
+		imageService: imageService,
+		provider:     provider,
+		downloadHD:   downloadHD,
+	}
+}
+
+// Source returns the name of the source the handler fetches from.
+func (sh *SourceHandler) Source() string {
+	return sh.provider.Name()
+}
+
+// FetchLatest fetches the most recently published record of the source.
+func (sh *SourceHandler) FetchLatest(ctx context.Context) (_ *model.Record, err error) {
+	ctx, span := tracing.Start(ctx, "SourceHandler.FetchLatest", attribute.String("source", sh.Source()))
+	defer func() { tracing.End(span, err) }()
+
+	record, err := sh.provider.Latest(ctx)
+	if err != nil {
+		return nil, service.Unavailable(err, "fetching the latest %s record", sh.Source())
+	}
+	return record, nil
+}
+
+// SaveRecord downloads the media of the provided record and saves it using the image service.
+// For images the picture itself is stored, for videos the embed URL and provider are recorded
+// and the thumbnail, if available, is stored as the image data. A failed HD download does not
+// prevent the standard rendition from being saved.
+func (sh *SourceHandler) SaveRecord(ctx context.Context, record *model.Record) (err error) {
+	ctx, span := tracing.Start(ctx, "SourceHandler.SaveRecord",
+		attribute.String("source", sh.Source()),
+		attribute.String("date", record.Date),
+		attribute.String("media_type", string(record.MediaType)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	image := &model.Image{
+		Source:         sh.provider.Name(),
+		Date:           record.Date,
+		Explanation:    record.Explanation,
+		MediaType:      record.MediaType,
+		Title:          record.Title,
+		URL:            record.URL,
+		HDURL:          record.HDURL,
+		Copyright:      strings.TrimSpace(record.Copyright),
+		ServiceVersion: record.ServiceVersion,
+	}
+
+	dataURL := record.URL
+	if record.MediaType != model.MediaTypeImage {
+		dataURL = record.ThumbnailURL
+	}
+	if record.MediaType == model.MediaTypeVideo {
+		image.Video = &model.Video{
+			Provider:     videoProvider(record.URL),
+			ThumbnailURL: record.ThumbnailURL,
+		}
+	}
+
+	if dataURL != "" {
+		imgData, err := sh.provider.Download(ctx, dataURL)
+		if err != nil {
+			return service.Unavailable(err, "downloading the %s image for date %s", sh.Source(), record.Date)
+		}
+		image.Data = imgData
+		metrics.ImageDownloadBytes.WithLabelValues(sh.Source(), "standard").Observe(float64(len(imgData)))
+	} else {
+		logging.FromContext(ctx).Warnf("No image to download for %s entry of date %s", record.MediaType, record.Date)
+	}
+
+	if sh.downloadHD && record.MediaType == model.MediaTypeImage && record.HDURL != "" && record.HDURL != record.URL {
+		hdData, err := sh.provider.Download(ctx, record.HDURL)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("Skipping HD image for date %s: %v", record.Date, err)
+		} else {
+			image.HDData = hdData
+			metrics.ImageDownloadBytes.WithLabelValues(sh.Source(), "hd").Observe(float64(len(hdData)))
+		}
+	}
+
+	return sh.imageService.Save(ctx, image)
+}
+
+// videoProvider returns the name of the provider hosting the video with the provided embed URL.
+func videoProvider(embedURL string) string {
+	u, err := url.Parse(embedURL)
+	if err != nil || u.Hostname() == "" {
+		return "unknown"
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch {
+	case host == "youtu.be" || strings.HasSuffix(host, "youtube.com") || strings.HasSuffix(host, "youtube-nocookie.com"):
+		return "youtube"
+	case strings.HasSuffix(host, "vimeo.com"):
+		return "vimeo"
+	default:
+		return host
+	}
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/provider"
+)
+
+func TestSourceHandler_SaveRecord(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.jpg":
+			w.Write([]byte{0xFF, 0xD8, 0xFF})
+		case "/image_hd.jpg":
+			w.Write([]byte{0xFF, 0xD8, 0xFF, 0xDB})
+		case "/thumbnail.jpg":
+			w.Write([]byte{0xFF, 0xD8, 0xFF, 0xE0})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name          string
+		record        *model.Record
+		downloadHD    bool
+		expectedImage *model.Image
+		wantErr       bool
+	}{
+		{
+			name:   "Image",
+			record: &model.Record{Date: "2024-05-18", Title: "A Beautiful Nebula", MediaType: "image", URL: srv.URL + "/image.jpg"},
+			expectedImage: &model.Image{
+				Source:    model.DefaultSource,
+				Date:      "2024-05-18",
+				Title:     "A Beautiful Nebula",
+				MediaType: "image",
+				URL:       srv.URL + "/image.jpg",
+				Data:      []byte{0xFF, 0xD8, 0xFF},
+			},
+		},
+		{
+			name: "ImageWithHD",
+			record: &model.Record{
+				Date:           "2024-05-18",
+				Title:          "A Beautiful Nebula",
+				MediaType:      "image",
+				URL:            srv.URL + "/image.jpg",
+				HDURL:          srv.URL + "/image_hd.jpg",
+				Copyright:      "\nJane Doe\n",
+				ServiceVersion: "v1",
+			},
+			downloadHD: true,
+			expectedImage: &model.Image{
+				Source:         model.DefaultSource,
+				Date:           "2024-05-18",
+				Title:          "A Beautiful Nebula",
+				MediaType:      "image",
+				URL:            srv.URL + "/image.jpg",
+				HDURL:          srv.URL + "/image_hd.jpg",
+				Copyright:      "Jane Doe",
+				ServiceVersion: "v1",
+				Data:           []byte{0xFF, 0xD8, 0xFF},
+				HDData:         []byte{0xFF, 0xD8, 0xFF, 0xDB},
+			},
+		},
+		{
+			name: "ImageWithMissingHD",
+			record: &model.Record{
+				Date:      "2024-05-18",
+				MediaType: "image",
+				URL:       srv.URL + "/image.jpg",
+				HDURL:     srv.URL + "/missing.jpg",
+			},
+			downloadHD: true,
+			expectedImage: &model.Image{
+				Source:    model.DefaultSource,
+				Date:      "2024-05-18",
+				MediaType: "image",
+				URL:       srv.URL + "/image.jpg",
+				HDURL:     srv.URL + "/missing.jpg",
+				Data:      []byte{0xFF, 0xD8, 0xFF},
+			},
+		},
+		{
+			name: "VideoWithThumbnail",
+			record: &model.Record{
+				Date:         "2024-05-19",
+				Title:        "A Beautiful Eclipse",
+				MediaType:    "video",
+				URL:          "https://www.youtube.com/embed/abcdef?rel=0",
+				ThumbnailURL: srv.URL + "/thumbnail.jpg",
+			},
+			expectedImage: &model.Image{
+				Source:    model.DefaultSource,
+				Date:      "2024-05-19",
+				Title:     "A Beautiful Eclipse",
+				MediaType: "video",
+				URL:       "https://www.youtube.com/embed/abcdef?rel=0",
+				Video:     &model.Video{Provider: "youtube", ThumbnailURL: srv.URL + "/thumbnail.jpg"},
+				Data:      []byte{0xFF, 0xD8, 0xFF, 0xE0},
+			},
+		},
+		{
+			name:   "VideoWithoutThumbnail",
+			record: &model.Record{Date: "2024-05-20", MediaType: "video", URL: "https://player.vimeo.com/video/123"},
+			expectedImage: &model.Image{
+				Source:    model.DefaultSource,
+				Date:      "2024-05-20",
+				MediaType: "video",
+				URL:       "https://player.vimeo.com/video/123",
+				Video:     &model.Video{Provider: "vimeo"},
+			},
+		},
+		{
+			name:    "DownloadError",
+			record:  &model.Record{Date: "2024-05-21", MediaType: "image", URL: srv.URL + "/missing.jpg"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *model.Image
+			imageService := &mockImageService{
+				SaveFunc: func(image *model.Image) error {
+					saved = image
+					return nil
+				},
+			}
+			sourceHandler := NewSourceHandler(imageService, provider.NewAPOD(model.DefaultSource, srv.Client(), "", "DEMO_KEY"), tt.downloadHD)
+
+			err := sourceHandler.SaveRecord(context.Background(), tt.record)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Nil(t, saved)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedImage, saved)
+		})
+	}
+}
+
+func TestVideoProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"https://www.youtube.com/embed/abcdef?rel=0":      "youtube",
+		"https://youtu.be/abcdef":                         "youtube",
+		"https://www.youtube-nocookie.com/embed/abcdef":   "youtube",
+		"https://player.vimeo.com/video/123":              "vimeo",
+		"https://apod.nasa.gov/apod/image/2405/movie.mp4": "apod.nasa.gov",
+		"not a url": "unknown",
+	}
+
+	for embedURL, expected := range tests {
+		require.Equal(t, expected, videoProvider(embedURL), embedURL)
+	}
+}
@@ -0,0 +1,228 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeCAFile writes the certificate of the TLS test server to a PEM file.
+func writeCAFile(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// writeClientCert generates a self-signed client certificate and writes it and its key to PEM files.
+func writeClientCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "young-astrologer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile, cert
+}
+
+func get(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestNew_VerifiesServerCertificate(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client, err := New(Config{})
+	require.NoError(t, err)
+	_, err = get(client, srv.URL)
+	var unknownAuthority x509.UnknownAuthorityError
+	require.ErrorAs(t, err, &unknownAuthority)
+
+	client, err = New(Config{CAFile: writeCAFile(t, srv)})
+	require.NoError(t, err)
+	body, err := get(client, srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "ok", body)
+}
+
+func TestNew_ClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	certFile, keyFile, clientCert := writeClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client, err := New(Config{CAFile: writeCAFile(t, srv)})
+	require.NoError(t, err)
+	_, err = get(client, srv.URL)
+	require.Error(t, err)
+
+	client, err = New(Config{CAFile: writeCAFile(t, srv), CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	body, err := get(client, srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "young-astrologer", body)
+}
+
+func TestNew_Proxy(t *testing.T) {
+	t.Parallel()
+
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	client, err := New(Config{ProxyURL: proxy.URL})
+	require.NoError(t, err)
+	body, err := get(client, "http://apod.example.com/image.jpg")
+	require.NoError(t, err)
+	require.Equal(t, "proxied", body)
+	require.Equal(t, "http://apod.example.com/image.jpg", proxied)
+}
+
+func TestNew_Timeout(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	client, err := New(Config{CAFile: writeCAFile(t, srv), Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	_, err = get(client, srv.URL)
+	require.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+func TestNew_MaxResponseSize(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.URL.Query().Get("body")
+		if r.URL.Query().Get("chunked") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		} else {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	client, err := New(Config{CAFile: writeCAFile(t, srv), MaxResponseSize: 4})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "within limit", query: "?body=abcd", want: "abcd"},
+		{name: "content length over limit", query: "?body=abcde", wantErr: true},
+		{name: "chunked within limit", query: "?body=abcd&chunked=1", want: "abcd"},
+		{name: "chunked over limit", query: "?body=abcdefgh&chunked=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := get(client, srv.URL+tt.query)
+			if tt.wantErr {
+				var tooLarge *ResponseTooLargeError
+				require.ErrorAs(t, err, &tooLarge)
+				require.Equal(t, int64(4), tooLarge.Limit)
+				require.False(t, tooLarge.Retryable())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, body)
+		})
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	emptyFile := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "missing CA bundle", cfg: Config{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: "reading CA bundle"},
+		{name: "empty CA bundle", cfg: Config{CAFile: emptyFile}, wantErr: "no certificates found"},
+		{name: "certificate without key", cfg: Config{CertFile: emptyFile}, wantErr: "loading client certificate"},
+		{name: "invalid proxy", cfg: Config{ProxyURL: "proxy:3128"}, wantErr: "invalid proxy URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(tt.cfg)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package httpserver provides the parts shared by the middleware of the HTTP server.
+package httpserver
+
+import (
+	"context"
+	"net/http"
+)
+
+// UnmatchedRoute is the route of the requests that match no pattern of the mux.
+const UnmatchedRoute = "unmatched"
+
+type routeKey struct{}
+
+// WithRoute wraps next so that the pattern of mux every request matches is resolved once, before the request
+// is served, and added to the request context for Route.
+func WithRoute(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+	})
+}
+
+// Route returns the pattern of the mux the request matches, as resolved by WithRoute, or UnmatchedRoute
+// if the request matches none or was not served by WithRoute. Labelling by pattern rather than path keeps
+// the number of distinct routes bounded.
+func Route(r *http.Request) string {
+	if route, ok := r.Context().Value(routeKey{}).(string); ok {
+		return route
+	}
+	return UnmatchedRoute
+}
+
+// ResponseRecorder records the status code and the size of the body written to the wrapped response writer.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// NewResponseRecorder returns a ResponseRecorder wrapping w.
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status code written, which is 200 if none was written explicitly.
+func (rr *ResponseRecorder) Status() int {
+	return rr.status
+}
+
+// Bytes returns the number of bytes of the body written.
+func (rr *ResponseRecorder) Bytes() int64 {
+	return rr.bytes
+}
+
+// WriteHeader records the status code and writes it to the wrapped response writer.
+func (rr *ResponseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the data to the wrapped response writer, implicitly with the 200 status code, and counts its bytes.
+func (rr *ResponseRecorder) Write(data []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(data)
+	rr.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped response writer for http.ResponseController.
+func (rr *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
@@ -0,0 +1,89 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRoute(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /images/{date}", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedRoute string
+	}{
+		{name: "Matched", path: "/images/2024-05-18", expectedRoute: "GET /images/{date}"},
+		{name: "Unmatched", path: "/videos", expectedRoute: UnmatchedRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var route string
+			handler := WithRoute(mux, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				route = Route(r)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, tt.expectedRoute, route)
+		})
+	}
+
+	require.Equal(t, UnmatchedRoute, Route(httptest.NewRequest(http.MethodGet, "/images/2024-05-18", nil)))
+}
+
+func TestResponseRecorder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		serve          func(w http.ResponseWriter)
+		expectedStatus int
+		expectedBytes  int64
+	}{
+		{
+			name:           "ImplicitStatus",
+			serve:          func(w http.ResponseWriter) { w.Write([]byte("ok")) },
+			expectedStatus: http.StatusOK,
+			expectedBytes:  2,
+		},
+		{
+			name: "ExplicitStatus",
+			serve: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte("not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBytes:  9,
+		},
+		{
+			name: "StatusAfterBody",
+			serve: func(w http.ResponseWriter) {
+				w.Write([]byte("ok"))
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBytes:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := NewResponseRecorder(httptest.NewRecorder())
+			tt.serve(recorder)
+
+			require.Equal(t, tt.expectedStatus, recorder.Status())
+			require.Equal(t, tt.expectedBytes, recorder.Bytes())
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+// paletteSampleSize is the size the image is downscaled to before its dominant color is determined.
+const paletteSampleSize = 64
+
+// Analyze describes the image data, which img and format were decoded from by Decode: its checksum, format,
+// dimensions, dominant color and EXIF fields.
+func Analyze(data []byte, img *image.RGBA, format string) model.ImageInfo {
+	return model.ImageInfo{
+		Checksum:      Checksum(data),
+		Format:        format,
+		Width:         img.Bounds().Dx(),
+		Height:        img.Bounds().Dy(),
+		DominantColor: DominantColor(img),
+		EXIF:          parseEXIF(data),
+	}
+}
+
+// Checksum returns the hex-encoded SHA-256 checksum of the data.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// DominantColor returns the most common color of the image in the #rrggbb form.
+// Colors are grouped into buckets of similar shades and the average of the largest bucket is returned;
+// mostly transparent pixels are ignored. It returns an empty string if the image is fully transparent.
+func DominantColor(img *image.RGBA) string {
+	sample := Fit(img, paletteSampleSize)
+
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	var buckets [4096]bucket
+
+	largest := -1
+	bounds := sample.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := sample.RGBAAt(x, y)
+			if c.A < 0x80 {
+				continue
+			}
+			n := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+			i := int(n.R>>4)<<8 | int(n.G>>4)<<4 | int(n.B>>4)
+			buckets[i].count++
+			buckets[i].r += int(n.R)
+			buckets[i].g += int(n.G)
+			buckets[i].b += int(n.B)
+			if largest < 0 || buckets[i].count > buckets[largest].count {
+				largest = i
+			}
+		}
+	}
+	if largest < 0 {
+		return ""
+	}
+
+	b := buckets[largest]
+	return fmt.Sprintf("#%02x%02x%02x", b.r/b.count, b.g/b.count, b.b/b.count)
+}
@@ -0,0 +1,188 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+// tiffField is a field of an image file directory written by buildTIFF.
+type tiffField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiField(tag uint16, value string) tiffField {
+	return tiffField{tag: tag, typ: typeASCII, count: uint32(len(value) + 1), data: append([]byte(value), 0)}
+}
+
+func rationalField(tag uint16, num, den uint32) tiffField {
+	data := binary.LittleEndian.AppendUint32(nil, num)
+	return tiffField{tag: tag, typ: typeRational, count: 1, data: binary.LittleEndian.AppendUint32(data, den)}
+}
+
+func shortField(tag uint16, value uint16) tiffField {
+	return tiffField{tag: tag, typ: typeShort, count: 1, data: binary.LittleEndian.AppendUint16(nil, value)}
+}
+
+// buildTIFF returns a little-endian TIFF structure with IFD0 and an EXIF sub-IFD holding the fields.
+func buildTIFF(ifd0, exifIFD []tiffField) []byte {
+	ifdSize := func(fields []tiffField) int { return 2 + 12*len(fields) + 4 }
+
+	ifd0 = append(ifd0, tiffField{tag: tagExifIFD, typ: typeLong, count: 1})
+	ifd0Offset := 8
+	exifOffset := ifd0Offset + ifdSize(ifd0)
+	dataOffset := exifOffset + ifdSize(exifIFD)
+	ifd0[len(ifd0)-1].data = binary.LittleEndian.AppendUint32(nil, uint32(exifOffset))
+
+	var out, data []byte
+	out = append(out, 'I', 'I')
+	out = binary.LittleEndian.AppendUint16(out, 42)
+	out = binary.LittleEndian.AppendUint32(out, uint32(ifd0Offset))
+	for _, fields := range [][]tiffField{ifd0, exifIFD} {
+		out = binary.LittleEndian.AppendUint16(out, uint16(len(fields)))
+		for _, field := range fields {
+			out = binary.LittleEndian.AppendUint16(out, field.tag)
+			out = binary.LittleEndian.AppendUint16(out, field.typ)
+			out = binary.LittleEndian.AppendUint32(out, field.count)
+			if len(field.data) <= 4 {
+				out = append(out, append(field.data, make([]byte, 4-len(field.data))...)...)
+				continue
+			}
+			out = binary.LittleEndian.AppendUint32(out, uint32(dataOffset+len(data)))
+			data = append(data, field.data...)
+		}
+		out = binary.LittleEndian.AppendUint32(out, 0)
+	}
+	return append(out, data...)
+}
+
+// withEXIF inserts an APP1 segment with the TIFF structure after the start of image marker of the JPEG data.
+func withEXIF(jpegData, tiffData []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), tiffData...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{R: 0x10, G: 0x20, B: 0xC0, A: 0xFF}
+			if x < 50 {
+				c = color.RGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}))
+
+	data := withEXIF(buf.Bytes(), buildTIFF(
+		[]tiffField{asciiField(tagMake, "Canon"), asciiField(tagModel, "EOS R5")},
+		[]tiffField{
+			rationalField(tagExposureTime, 1, 250),
+			rationalField(tagFNumber, 28, 10),
+			shortField(tagISO, 1600),
+			asciiField(tagDateTimeOriginal, "2024:05:17 23:41:05"),
+			rationalField(tagFocalLength, 135, 1),
+		},
+	))
+
+	decoded, format, err := Decode(data)
+	require.NoError(t, err)
+	info := Analyze(data, decoded, format)
+	require.Equal(t, "jpeg", info.Format)
+	require.Equal(t, 300, info.Width)
+	require.Equal(t, 200, info.Height)
+	require.Len(t, info.Checksum, 64)
+	require.Equal(t, &model.EXIF{
+		CameraMake:   "Canon",
+		CameraModel:  "EOS R5",
+		ExposureTime: "1/250",
+		FNumber:      2.8,
+		ISO:          1600,
+		FocalLength:  135,
+		CapturedAt:   "2024-05-17T23:41:05",
+	}, info.EXIF)
+
+	// JPEG compression shifts the colors slightly, so only the bucket is checked.
+	require.Regexp(t, `^#[01][0-9a-f][12][0-9a-f][bc][0-9a-f]$`, info.DominantColor)
+}
+
+func TestAnalyze_WithoutEXIF(t *testing.T) {
+	t.Parallel()
+
+	data := encodeTestImage(t, "png", 10, 20, 0xFF)
+	decoded, format, err := Decode(data)
+	require.NoError(t, err)
+	info := Analyze(data, decoded, format)
+	require.Equal(t, "png", info.Format)
+	require.Equal(t, 10, info.Width)
+	require.Equal(t, 20, info.Height)
+	require.Nil(t, info.EXIF)
+}
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("<html></html>")
+	checksum := sha256.Sum256(data)
+	require.Equal(t, hex.EncodeToString(checksum[:]), Checksum(data))
+}
+
+func TestParseEXIF_Malformed(t *testing.T) {
+	t.Parallel()
+
+	jpegData := encodeTestImage(t, "jpeg", 8, 8, 0xFF)
+	tiffData := buildTIFF([]tiffField{asciiField(tagMake, "Canon")}, []tiffField{rationalField(tagFNumber, 28, 0)})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *model.EXIF
+	}{
+		{name: "ZeroDenominator", data: withEXIF(jpegData, tiffData), expected: &model.EXIF{CameraMake: "Canon"}},
+		{name: "Truncated", data: withEXIF(jpegData, tiffData[:20]), expected: nil},
+		{name: "BadByteOrder", data: withEXIF(jpegData, append([]byte("XX"), tiffData[2:]...)), expected: nil},
+		{name: "NoEXIF", data: jpegData, expected: nil},
+		{
+			name:     "InvalidUTF8",
+			data:     withEXIF(jpegData, buildTIFF([]tiffField{asciiField(tagMake, "Nikon \xa9\xff"), asciiField(tagModel, "\xe9")}, nil)),
+			expected: &model.EXIF{CameraMake: "Nikon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, parseEXIF(tt.data))
+		})
+	}
+}
+
+func TestFormatExposure(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "1/250", formatExposure(1, 250))
+	require.Equal(t, "1/3", formatExposure(10, 30))
+	require.Equal(t, "30", formatExposure(30, 1))
+	require.Equal(t, "2.5", formatExposure(5, 2))
+}
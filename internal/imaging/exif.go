@@ -0,0 +1,229 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+// TIFF tags of the EXIF fields that are extracted.
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagExifIFD          = 0x8769
+	tagExposureTime     = 0x829A
+	tagFNumber          = 0x829D
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagFocalLength      = 0x920A
+)
+
+// TIFF field types of the EXIF fields that are extracted.
+const (
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+// exifDateLayout is the layout of EXIF date and time fields.
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// parseEXIF extracts the camera, exposure and capture time fields from the EXIF block of JPEG or PNG data.
+// It returns nil if the data has no EXIF block or none of the fields is set. Malformed fields are ignored.
+func parseEXIF(data []byte) *model.EXIF {
+	block := findEXIF(data)
+	if block == nil {
+		return nil
+	}
+
+	t := newTIFF(block)
+	if t == nil {
+		return nil
+	}
+
+	var exif model.EXIF
+	ifd0 := t.readIFD(t.order.Uint32(block[4:8]))
+	exif.CameraMake = t.ascii(ifd0[tagMake])
+	exif.CameraModel = t.ascii(ifd0[tagModel])
+
+	if pointer, ok := t.uint(ifd0[tagExifIFD]); ok {
+		sub := t.readIFD(pointer)
+		if num, den, ok := t.rational(sub[tagExposureTime]); ok {
+			exif.ExposureTime = formatExposure(num, den)
+		}
+		if num, den, ok := t.rational(sub[tagFNumber]); ok {
+			exif.FNumber = float64(num) / float64(den)
+		}
+		if iso, ok := t.uint(sub[tagISO]); ok {
+			exif.ISO = int(iso)
+		}
+		if num, den, ok := t.rational(sub[tagFocalLength]); ok {
+			exif.FocalLength = float64(num) / float64(den)
+		}
+		if captured, err := time.Parse(exifDateLayout, t.ascii(sub[tagDateTimeOriginal])); err == nil {
+			exif.CapturedAt = captured.Format(model.EXIFTimeLayout)
+		}
+	}
+
+	if exif == (model.EXIF{}) {
+		return nil
+	}
+	return &exif
+}
+
+// findEXIF returns the TIFF structure of the EXIF block of JPEG data or of the eXIf chunk of PNG data, if any.
+func findEXIF(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		for offset := 2; offset+4 <= len(data) && data[offset] == 0xFF; {
+			marker := data[offset+1]
+			length := int(binary.BigEndian.Uint16(data[offset+2:]))
+			// Image data follows the start of scan marker; no metadata comes after it.
+			if marker == 0xDA || length < 2 || offset+2+length > len(data) {
+				return nil
+			}
+			segment := data[offset+4 : offset+2+length]
+			if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				return segment[6:]
+			}
+			offset += 2 + length
+		}
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		for offset := 8; offset+12 <= len(data); {
+			length := int(binary.BigEndian.Uint32(data[offset:]))
+			if length < 0 || offset+12+length > len(data) {
+				return nil
+			}
+			if string(data[offset+4:offset+8]) == "eXIf" {
+				return data[offset+8 : offset+8+length]
+			}
+			offset += 12 + length
+		}
+	}
+	return nil
+}
+
+// tiff reads fields of a TIFF structure.
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// entry is a field of an image file directory.
+type entry struct {
+	typ   uint16
+	count uint32
+	// value holds the value of the field if it fits in four bytes, and its offset otherwise.
+	value []byte
+}
+
+func newTIFF(data []byte) *tiff {
+	if len(data) < 8 {
+		return nil
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil
+	}
+	return &tiff{data: data, order: order}
+}
+
+// readIFD returns the fields of the image file directory at the offset by tag.
+func (t *tiff) readIFD(offset uint32) map[uint16]entry {
+	entries := make(map[uint16]entry)
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return entries
+	}
+
+	count := int(t.order.Uint16(t.data[offset:]))
+	for i := 0; i < count; i++ {
+		start := uint64(offset) + 2 + uint64(i)*12
+		if start+12 > uint64(len(t.data)) {
+			break
+		}
+		field := t.data[start : start+12]
+		entries[t.order.Uint16(field)] = entry{
+			typ:   t.order.Uint16(field[2:]),
+			count: t.order.Uint32(field[4:]),
+			value: field[8:12],
+		}
+	}
+	return entries
+}
+
+// bytes returns the value of the field, which consists of size bytes.
+func (t *tiff) bytes(e entry, size uint32) []byte {
+	if size <= 4 {
+		return e.value[:size]
+	}
+	offset := uint64(t.order.Uint32(e.value))
+	if offset+uint64(size) > uint64(len(t.data)) {
+		return nil
+	}
+	return t.data[offset : offset+uint64(size)]
+}
+
+// ascii returns the value of an ASCII field without its terminating NUL and surrounding spaces.
+// Bytes that are not valid UTF-8, which cameras writing Latin-1 or garbage produce, are dropped, as the
+// value is stored in text columns.
+func (t *tiff) ascii(e entry) string {
+	if e.typ != typeASCII || e.count == 0 || e.count > 1024 {
+		return ""
+	}
+	value, _, _ := strings.Cut(string(t.bytes(e, e.count)), "\x00")
+	return strings.TrimSpace(strings.ToValidUTF8(value, ""))
+}
+
+// uint returns the first value of a SHORT or LONG field.
+func (t *tiff) uint(e entry) (uint32, bool) {
+	if e.count == 0 {
+		return 0, false
+	}
+	switch e.typ {
+	case typeShort:
+		return uint32(t.order.Uint16(e.value)), true
+	case typeLong:
+		return t.order.Uint32(e.value), true
+	default:
+		return 0, false
+	}
+}
+
+// rational returns the numerator and the non-zero denominator of a RATIONAL field.
+func (t *tiff) rational(e entry) (uint32, uint32, bool) {
+	if e.typ != typeRational || e.count == 0 {
+		return 0, 0, false
+	}
+	value := t.bytes(e, 8)
+	if value == nil {
+		return 0, 0, false
+	}
+	num, den := t.order.Uint32(value), t.order.Uint32(value[4:])
+	return num, den, den != 0
+}
+
+// formatExposure formats an exposure time in seconds as a fraction such as 1/250, or as a decimal for long exposures.
+func formatExposure(num, den uint32) string {
+	if num == 0 {
+		return "0"
+	}
+	if num < den {
+		return "1/" + strconv.FormatFloat(math.Round(float64(den)/float64(num)*10)/10, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(math.Round(float64(num)/float64(den)*10)/10, 'f', -1, 64)
+}
@@ -0,0 +1,156 @@
+// Package imaging generates downscaled renditions of images in pure Go.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder.
+	"image/jpeg"
+	"image/png"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+const (
+	// maxPixels bounds the size of the images that are decoded, guarding against decompression bombs.
+	maxPixels = 64 << 20
+
+	jpegQuality = 85
+)
+
+// ErrUnsupported is returned when data is not a JPEG, PNG or GIF image that can be decoded.
+var ErrUnsupported = errors.New("unsupported image")
+
+// Spec describes a rendition by its name and the size of the square its longest side is fitted into.
+type Spec struct {
+	Name    string
+	MaxSize int
+}
+
+// Specs lists the renditions generated for every image.
+var Specs = []Spec{
+	{Name: model.RenditionThumb, MaxSize: 320},
+	{Name: model.RenditionMedium, MaxSize: 1024},
+}
+
+// Decode decodes a JPEG, PNG or GIF image and returns it along with its format. Only the first frame of
+// animated GIFs is decoded. If the data is not a supported image or has more pixels than are decoded,
+// the returned error wraps ErrUnsupported.
+func Decode(data []byte) (*image.RGBA, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d pixels", ErrUnsupported, config.Width, config.Height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return toRGBA(decoded), format, nil
+}
+
+// Generate returns a rendition of the decoded image for each of the specs.
+// Images are never upscaled. Opaque renditions are encoded as JPEG and renditions with transparency as PNG.
+func Generate(src *image.RGBA, specs []Spec) ([]*model.Rendition, error) {
+	renditions := make([]*model.Rendition, 0, len(specs))
+	for _, spec := range specs {
+		img := Fit(src, spec.MaxSize)
+		encoded, contentType, err := encode(img)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s rendition: %w", spec.Name, err)
+		}
+		renditions = append(renditions, &model.Rendition{
+			Name:        spec.Name,
+			ContentType: contentType,
+			Width:       img.Bounds().Dx(),
+			Height:      img.Bounds().Dy(),
+			Data:        encoded,
+			Size:        int64(len(encoded)),
+		})
+	}
+	return renditions, nil
+}
+
+// Fit downscales the image so that its longest side is at most size pixels, preserving its aspect ratio.
+// Images that already fit are returned unchanged.
+func Fit(src *image.RGBA, size int) *image.RGBA {
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	if width <= size && height <= size {
+		return src
+	}
+
+	if width >= height {
+		height = max(1, (height*size+width/2)/width)
+		width = size
+	} else {
+		width = max(1, (width*size+height/2)/height)
+		height = size
+	}
+	return resize(src, width, height)
+}
+
+// resize downscales the image to the dimensions by averaging the source pixels covered by each destination pixel.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var r, g, b, a uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(bounds.Min.X+x0, bounds.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[offset])
+					g += uint64(src.Pix[offset+1])
+					b += uint64(src.Pix[offset+2])
+					a += uint64(src.Pix[offset+3])
+					offset += 4
+				}
+			}
+
+			n := uint64((y1 - y0) * (x1 - x0))
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8((r + n/2) / n)
+			dst.Pix[offset+1] = uint8((g + n/2) / n)
+			dst.Pix[offset+2] = uint8((b + n/2) / n)
+			dst.Pix[offset+3] = uint8((a + n/2) / n)
+		}
+	}
+	return dst
+}
+
+// toRGBA converts the image to RGBA with its origin at zero.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// encode encodes the image as JPEG if it is opaque and as PNG otherwise, and returns the data and its content type.
+func encode(img *image.RGBA) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if img.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
@@ -0,0 +1,139 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodeTestImage(t *testing.T, format string, width, height int, alpha uint8) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: alpha})
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+	case "png":
+		require.NoError(t, png.Encode(&buf, img))
+	case "gif":
+		require.NoError(t, gif.Encode(&buf, img, nil))
+	}
+	return buf.Bytes()
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		data                []byte
+		expectedContentType string
+		expectedSizes       [][2]int
+	}{
+		{
+			name:                "LandscapeJPEG",
+			data:                encodeTestImage(t, "jpeg", 2000, 1000, 0xFF),
+			expectedContentType: "image/jpeg",
+			expectedSizes:       [][2]int{{320, 160}, {1024, 512}},
+		},
+		{
+			name:                "PortraitGIF",
+			data:                encodeTestImage(t, "gif", 300, 1200, 0xFF),
+			expectedContentType: "image/jpeg",
+			expectedSizes:       [][2]int{{80, 320}, {256, 1024}},
+		},
+		{
+			name:                "TransparentPNG",
+			data:                encodeTestImage(t, "png", 640, 640, 0x80),
+			expectedContentType: "image/png",
+			expectedSizes:       [][2]int{{320, 320}, {640, 640}},
+		},
+		{
+			name:                "SmallPNG",
+			data:                encodeTestImage(t, "png", 100, 50, 0xFF),
+			expectedContentType: "image/jpeg",
+			expectedSizes:       [][2]int{{100, 50}, {100, 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, _, err := Decode(tt.data)
+			require.NoError(t, err)
+			renditions, err := Generate(decoded, Specs)
+			require.NoError(t, err)
+			require.Len(t, renditions, len(Specs))
+
+			for i, rendition := range renditions {
+				require.Equal(t, Specs[i].Name, rendition.Name)
+				require.Equal(t, tt.expectedContentType, rendition.ContentType)
+				require.Equal(t, tt.expectedContentType, http.DetectContentType(rendition.Data))
+				require.Equal(t, int64(len(rendition.Data)), rendition.Size)
+
+				config, _, err := image.DecodeConfig(bytes.NewReader(rendition.Data))
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedSizes[i], [2]int{config.Width, config.Height})
+				require.Equal(t, tt.expectedSizes[i], [2]int{rendition.Width, rendition.Height})
+			}
+		})
+	}
+}
+
+func TestDecode_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "HTML",
+			data: []byte("<html></html>"),
+		},
+		{
+			name: "Truncated",
+			data: encodeTestImage(t, "png", 100, 50, 0xFF)[:64],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := Decode(tt.data)
+			require.ErrorIs(t, err, ErrUnsupported)
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 2; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x * 60), G: 100, B: 0, A: 0xFF})
+		}
+	}
+
+	dst := Fit(src, 2)
+	require.Equal(t, image.Rect(0, 0, 2, 1), dst.Bounds())
+	require.Equal(t, color.RGBA{R: 30, G: 100, B: 0, A: 0xFF}, dst.RGBAAt(0, 0))
+	require.Equal(t, color.RGBA{R: 150, G: 100, B: 0, A: 0xFF}, dst.RGBAAt(1, 0))
+
+	require.Same(t, src, Fit(src, 4))
+}
@@ -0,0 +1,362 @@
+// Package job provides the background jobs that keep the image library up to date.
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+	_ "time/tzdata"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/metrics"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/provider"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+	"github.com/EgMeln/YoungAstrologer/internal/tracing"
+)
+
+// Names of the jobs recorded in the job run history.
+const (
+	DailyJob    = "daily"
+	BackfillJob = "backfill"
+)
+
+const dateLayout = "2006-01-02"
+
+// apodLocation is the time zone in which APOD days start; NASA publishes around midnight US Eastern.
+var apodLocation = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// APODDate returns the APOD day that is current at the provided time.
+func APODDate(t time.Time) string {
+	return t.In(apodLocation).Format(dateLayout)
+}
+
+// APODDayStart returns the time at which the APOD day that is current at the provided time started.
+func APODDayStart(t time.Time) time.Time {
+	year, month, day := t.In(apodLocation).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, apodLocation)
+}
+
+// Fetcher fetches the records of a source and saves the missing ones.
+type Fetcher interface {
+	Source() string
+	FetchLatest(ctx context.Context) (*model.Record, error)
+	Backfill(ctx context.Context, startDate, endDate string) (int, error)
+}
+
+// RetryPolicy defines how failed attempts are retried with exponential backoff and jitter.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used for the daily fetch.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 6,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    30 * time.Minute,
+}
+
+// PollPolicy defines how long a daily run waits for the APOD of its day to be published.
+// Polling is disabled when Interval is zero.
+type PollPolicy struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// notPublishedError is returned when the APOD of a day was not published before the poll timeout.
+type notPublishedError struct {
+	date   string
+	latest string
+}
+
+func (e *notPublishedError) Error() string {
+	return fmt.Sprintf("APOD for %s is not published yet; latest is %s", e.date, e.latest)
+}
+
+// Retryable reports that polling already waited as long as allowed.
+func (e *notPublishedError) Retryable() bool {
+	return false
+}
+
+// recordTimeout bounds how long recording a job run may take after the run itself was canceled.
+const recordTimeout = 10 * time.Second
+
+// Runner runs the fetch jobs with retries and records the outcome of every run.
+type Runner struct {
+	fetcher     Fetcher
+	jobService  service.JobService
+	retry       RetryPolicy
+	poll        PollPolicy
+	catchUpDays int
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// NewRunner creates a new Runner for the source of the fetcher. Every daily run also fetches
+// the entries of the preceding catchUpDays days that are missing from the library.
+func NewRunner(fetcher Fetcher, jobService service.JobService, retry RetryPolicy, poll PollPolicy, catchUpDays int) *Runner {
+	return &Runner{
+		fetcher:     fetcher,
+		jobService:  jobService,
+		retry:       retry,
+		poll:        poll,
+		catchUpDays: catchUpDays,
+		now:         time.Now,
+		sleep:       sleep,
+	}
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RunDaily fetches the entry of the provided day and any entries missing within the catch-up window before it.
+// If polling is enabled, it waits until the entry is published. It returns the number of newly saved images.
+func (r *Runner) RunDaily(ctx context.Context, date string) (saved int, err error) {
+	ctx = r.withFields(ctx, DailyJob, log.Fields{"apod_date": date})
+	ctx, span := r.startSpan(ctx, DailyJob, attribute.String("apod_date", date))
+	defer func() { tracing.End(span, err) }()
+
+	return r.run(ctx, DailyJob, date, func() (int, error) {
+		record, err := r.fetchPublished(ctx, date)
+		if err != nil {
+			return 0, err
+		}
+
+		var notPublished error
+		if record.Date < date {
+			notPublished = &notPublishedError{date: date, latest: record.Date}
+		}
+
+		latest, err := time.Parse(dateLayout, record.Date)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s date %q: %w", r.fetcher.Source(), record.Date, err)
+		}
+
+		saved, err := r.fetcher.Backfill(ctx, latest.AddDate(0, 0, -r.catchUpDays).Format(dateLayout), record.Date)
+		if err != nil {
+			return saved, err
+		}
+		return saved, notPublished
+	})
+}
+
+// fetchPublished fetches the latest entry, polling until the entry of the provided day
+// is published or the poll timeout expires.
+func (r *Runner) fetchPublished(ctx context.Context, date string) (*model.Record, error) {
+	deadline := r.now().Add(r.poll.Timeout)
+	for {
+		record, err := r.fetcher.FetchLatest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if record.Date >= date || r.poll.Interval <= 0 || !r.now().Add(r.poll.Interval).Before(deadline) {
+			return record, nil
+		}
+
+		logging.FromContext(ctx).Infof("%s entry for %s is not published yet; polling again in %s", r.fetcher.Source(), date, r.poll.Interval)
+		if err := r.sleep(ctx, r.poll.Interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Backfill fetches the entries missing between startDate and endDate (inclusive).
+// It returns the number of newly saved images.
+func (r *Runner) Backfill(ctx context.Context, startDate, endDate string) (saved int, err error) {
+	ctx = r.withFields(ctx, BackfillJob, log.Fields{"start_date": startDate, "end_date": endDate})
+	ctx, span := r.startSpan(ctx, BackfillJob, attribute.String("start_date", startDate), attribute.String("end_date", endDate))
+	defer func() { tracing.End(span, err) }()
+
+	return r.run(ctx, BackfillJob, "", func() (int, error) {
+		return r.fetcher.Backfill(ctx, startDate, endDate)
+	})
+}
+
+// Start runs the daily job according to the schedule until the context is done.
+// If today's scheduled time has already passed on startup, the daily job runs immediately.
+// A day whose daily run has already succeeded, possibly before a restart, is never fetched again.
+// A run in progress when the context is done is given gracePeriod to finish before it is canceled.
+func (r *Runner) Start(ctx context.Context, schedule *Schedule, gracePeriod time.Duration) {
+	runCtx, cancel := context.WithCancel(logging.WithFields(context.WithoutCancel(ctx), log.Fields{"source": r.fetcher.Source()}))
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(gracePeriod, cancel)
+	})
+	defer stop()
+
+	if r.due(schedule) {
+		r.runScheduled(runCtx)
+	}
+
+	for {
+		next := schedule.Next(r.now())
+		if next.IsZero() {
+			logging.FromContext(runCtx).Errorf("Schedule %s never fires again", schedule)
+			return
+		}
+		logging.FromContext(runCtx).Infof("Next %s fetch scheduled at %s", r.fetcher.Source(), next)
+
+		timer := time.NewTimer(next.Sub(r.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		r.runScheduled(runCtx)
+	}
+}
+
+// due reports whether the schedule has already fired today in its time zone.
+func (r *Runner) due(schedule *Schedule) bool {
+	now := r.now().In(schedule.Location())
+	year, month, day := now.Date()
+	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, schedule.Location())
+
+	first := schedule.Next(startOfDay.Add(-time.Minute))
+	return !first.IsZero() && !first.After(now)
+}
+
+// runScheduled runs the daily job for the current APOD day unless it has already succeeded.
+func (r *Runner) runScheduled(ctx context.Context) {
+	date := APODDate(r.now())
+	source := r.fetcher.Source()
+	entry := logging.FromContext(ctx).WithFields(log.Fields{"job": DailyJob, "apod_date": date})
+
+	succeeded, err := r.jobService.HasSucceeded(ctx, DailyJob, source, date)
+	if err != nil {
+		entry.Errorf("Error checking daily job runs of %s for %s: %v", source, date, err)
+		return
+	}
+	if succeeded {
+		entry.Infof("%s entry for %s has already been fetched", source, date)
+		return
+	}
+
+	entry.Infof("Fetching %s entry for %s...", source, date)
+	saved, err := r.RunDaily(ctx, date)
+	if err != nil {
+		entry.Errorf("Error fetching %s entry for %s: %v", source, date, err)
+		return
+	}
+	entry.Infof("%s entry for %s fetched: %d images saved", source, date, saved)
+}
+
+// run executes fn with retries and records the outcome as a run of the named job.
+// Retrying stops as soon as the context is done.
+func (r *Runner) run(ctx context.Context, job, apodDate string, fn func() (int, error)) (int, error) {
+	jobRun := &model.JobRun{
+		Job:       job,
+		Source:    r.fetcher.Source(),
+		APODDate:  apodDate,
+		StartedAt: r.now().UTC(),
+	}
+
+	var (
+		saved int
+		err   error
+	)
+	for jobRun.Attempts = 1; ; jobRun.Attempts++ {
+		var n int
+		n, err = fn()
+		err = provider.RedactError(err)
+		saved += n
+		if err == nil || jobRun.Attempts >= r.retry.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+
+		delay := r.backoff(jobRun.Attempts, err)
+		logging.FromContext(ctx).Warnf("Attempt %d of %s job for %s failed: %v; retrying in %s", jobRun.Attempts, job, jobRun.Source, err, delay)
+		if r.sleep(ctx, delay) != nil {
+			break
+		}
+	}
+
+	jobRun.FinishedAt = r.now().UTC()
+	jobRun.Saved = saved
+	jobRun.Status = model.JobStatusSuccess
+	if err != nil {
+		jobRun.Status = model.JobStatusFailed
+		jobRun.Error = service.Describe(err)
+	}
+
+	metrics.JobRuns.WithLabelValues(job, jobRun.Source, string(jobRun.Status)).Inc()
+	metrics.JobDuration.WithLabelValues(job, jobRun.Source, string(jobRun.Status)).Observe(jobRun.FinishedAt.Sub(jobRun.StartedAt).Seconds())
+
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	if recordErr := r.jobService.Record(recordCtx, jobRun); recordErr != nil {
+		logging.FromContext(ctx).Errorf("Error recording %s job run: %v", job, recordErr)
+	}
+
+	return saved, err
+}
+
+// withFields returns a copy of ctx whose logger adds the job, the source of the runner and the fields.
+func (r *Runner) withFields(ctx context.Context, job string, fields log.Fields) context.Context {
+	fields["job"] = job
+	fields["source"] = r.fetcher.Source()
+	return logging.WithFields(ctx, fields)
+}
+
+// startSpan starts the span of a run of the job, which is the root of the spans of the fetches it makes.
+func (r *Runner) startSpan(ctx context.Context, job string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("job", job), attribute.String("source", r.fetcher.Source()))
+	return tracing.Start(ctx, "job "+job, attrs...)
+}
+
+// backoff returns the delay before the next attempt. A delay requested by the server takes precedence;
+// otherwise the delay grows exponentially with the attempt number and is randomized to avoid bursts.
+func (r *Runner) backoff(attempt int, err error) time.Duration {
+	var withRetryAfter interface{ RetryAfter() time.Duration }
+	if errors.As(err, &withRetryAfter) {
+		if retryAfter := withRetryAfter.RetryAfter(); retryAfter > 0 {
+			return retryAfter
+		}
+	}
+
+	delay := r.retry.BaseDelay << (attempt - 1)
+	if delay > r.retry.MaxDelay || delay <= 0 {
+		delay = r.retry.MaxDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// retryable reports whether the error may be resolved by retrying.
+// Errors that do not tell whether they are retryable, such as network errors, are retried.
+func retryable(err error) bool {
+	var withRetryable interface{ Retryable() bool }
+	if errors.As(err, &withRetryable) {
+		return withRetryable.Retryable()
+	}
+	return true
+}
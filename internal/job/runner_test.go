@@ -0,0 +1,369 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/service"
+)
+
+type mockFetcher struct {
+	FetchLatestFunc func() (*model.Record, error)
+	BackfillFunc    func(startDate, endDate string) (int, error)
+}
+
+func (m *mockFetcher) Source() string {
+	return model.DefaultSource
+}
+
+func (m *mockFetcher) FetchLatest(ctx context.Context) (*model.Record, error) {
+	return m.FetchLatestFunc()
+}
+
+func (m *mockFetcher) Backfill(ctx context.Context, startDate, endDate string) (int, error) {
+	return m.BackfillFunc(startDate, endDate)
+}
+
+type mockJobService struct {
+	runs      []*model.JobRun
+	succeeded map[string]bool
+}
+
+func (m *mockJobService) Record(ctx context.Context, run *model.JobRun) error {
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *mockJobService) List(ctx context.Context, job string, limit int) ([]*model.JobRun, error) {
+	return m.runs, nil
+}
+
+func (m *mockJobService) HasSucceeded(ctx context.Context, job, source, apodDate string) (bool, error) {
+	return m.succeeded[job+" "+source+" "+apodDate], nil
+}
+
+func (m *mockJobService) Latest(ctx context.Context, job, source, status string) (*model.JobRun, error) {
+	return nil, service.NotFound("no matching runs of %s", source)
+}
+
+type statusError struct {
+	retryable  bool
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string             { return "status error" }
+func (e *statusError) Retryable() bool           { return e.retryable }
+func (e *statusError) RetryAfter() time.Duration { return e.retryAfter }
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    3 * time.Second,
+}
+
+func newTestRunner(fetcher Fetcher, jobService *mockJobService) (*Runner, *[]time.Duration) {
+	var delays []time.Duration
+	now := time.Date(2024, 5, 18, 5, 30, 0, 0, apodLocation)
+	runner := NewRunner(fetcher, jobService, testRetryPolicy, PollPolicy{}, 7)
+	runner.now = func() time.Time {
+		return now
+	}
+	runner.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		now = now.Add(d)
+		return ctx.Err()
+	}
+	return runner, &delays
+}
+
+func TestRunner_RunDaily(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			attempts++
+			switch attempts {
+			case 1:
+				return nil, errors.New("connection reset")
+			case 2:
+				return nil, &statusError{retryable: true, retryAfter: 90 * time.Second}
+			}
+			return &model.Record{Date: "2024-05-18"}, nil
+		},
+		BackfillFunc: func(startDate, endDate string) (int, error) {
+			require.Equal(t, "2024-05-11", startDate)
+			require.Equal(t, "2024-05-18", endDate)
+			return 3, nil
+		},
+	}
+	jobService := &mockJobService{}
+	runner, delays := newTestRunner(fetcher, jobService)
+
+	saved, err := runner.RunDaily(context.Background(), "2024-05-18")
+	require.NoError(t, err)
+	require.Equal(t, 3, saved)
+
+	require.Len(t, *delays, 2)
+	require.GreaterOrEqual(t, (*delays)[0], 500*time.Millisecond)
+	require.LessOrEqual(t, (*delays)[0], time.Second)
+	require.Equal(t, 90*time.Second, (*delays)[1])
+
+	require.Len(t, jobService.runs, 1)
+	require.Equal(t, DailyJob, jobService.runs[0].Job)
+	require.Equal(t, "2024-05-18", jobService.runs[0].APODDate)
+	require.Equal(t, model.JobStatusSuccess, jobService.runs[0].Status)
+	require.Equal(t, 3, jobService.runs[0].Attempts)
+	require.Equal(t, 3, jobService.runs[0].Saved)
+}
+
+func TestRunner_RunDailyGivesUp(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+	jobService := &mockJobService{}
+	runner, delays := newTestRunner(fetcher, jobService)
+
+	_, err := runner.RunDaily(context.Background(), "2024-05-18")
+	require.Error(t, err)
+
+	require.Len(t, *delays, testRetryPolicy.MaxAttempts-1)
+	for _, delay := range *delays {
+		require.LessOrEqual(t, delay, testRetryPolicy.MaxDelay)
+	}
+
+	require.Len(t, jobService.runs, 1)
+	require.Equal(t, model.JobStatusFailed, jobService.runs[0].Status)
+	require.Equal(t, testRetryPolicy.MaxAttempts, jobService.runs[0].Attempts)
+	require.Equal(t, "connection refused", jobService.runs[0].Error)
+}
+
+func TestRunner_RunDailyRecordsSafeErrors(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			cause := &url.Error{Op: "Get", URL: "https://api.nasa.gov/planetary/apod?api_key=SECRET_KEY", Err: errors.New("connection refused")}
+			return nil, service.Unavailable(cause, "NASA API is unavailable")
+		},
+	}
+	jobService := &mockJobService{}
+	runner, _ := newTestRunner(fetcher, jobService)
+
+	_, err := runner.RunDaily(context.Background(), "2024-05-18")
+	require.ErrorIs(t, err, service.ErrUnavailable)
+	require.NotContains(t, err.Error(), "SECRET_KEY")
+	require.Equal(t, "upstream unavailable: NASA API is unavailable", jobService.runs[0].Error)
+}
+
+func TestRunner_RunDailyNotRetryable(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			return nil, &statusError{retryable: false}
+		},
+	}
+	jobService := &mockJobService{}
+	runner, delays := newTestRunner(fetcher, jobService)
+
+	_, err := runner.RunDaily(context.Background(), "2024-05-18")
+	require.Error(t, err)
+	require.Empty(t, *delays)
+	require.Equal(t, 1, jobService.runs[0].Attempts)
+}
+
+func TestRunner_Backfill(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	fetcher := &mockFetcher{
+		BackfillFunc: func(startDate, endDate string) (int, error) {
+			attempts++
+			if attempts == 1 {
+				return 2, errors.New("failed to save 1 images")
+			}
+			return 1, nil
+		},
+	}
+	jobService := &mockJobService{}
+	runner, _ := newTestRunner(fetcher, jobService)
+
+	saved, err := runner.Backfill(context.Background(), "2024-05-01", "2024-05-03")
+	require.NoError(t, err)
+	require.Equal(t, 3, saved)
+	require.Equal(t, BackfillJob, jobService.runs[0].Job)
+	require.Equal(t, 2, jobService.runs[0].Attempts)
+}
+
+func TestRunner_RunDailyPollsUntilPublished(t *testing.T) {
+	t.Parallel()
+
+	fetches := 0
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			fetches++
+			if fetches < 3 {
+				return &model.Record{Date: "2024-05-17"}, nil
+			}
+			return &model.Record{Date: "2024-05-18"}, nil
+		},
+		BackfillFunc: func(startDate, endDate string) (int, error) {
+			require.Equal(t, "2024-05-18", endDate)
+			return 1, nil
+		},
+	}
+	jobService := &mockJobService{}
+	runner, delays := newTestRunner(fetcher, jobService)
+	runner.poll = PollPolicy{Interval: 10 * time.Minute, Timeout: time.Hour}
+
+	saved, err := runner.RunDaily(context.Background(), "2024-05-18")
+	require.NoError(t, err)
+	require.Equal(t, 1, saved)
+	require.Equal(t, []time.Duration{10 * time.Minute, 10 * time.Minute}, *delays)
+}
+
+func TestRunner_RunDailyPollTimeout(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			return &model.Record{Date: "2024-05-17"}, nil
+		},
+		BackfillFunc: func(startDate, endDate string) (int, error) {
+			require.Equal(t, "2024-05-17", endDate)
+			return 0, nil
+		},
+	}
+	jobService := &mockJobService{}
+	runner, delays := newTestRunner(fetcher, jobService)
+	runner.poll = PollPolicy{Interval: 20 * time.Minute, Timeout: time.Hour}
+
+	_, err := runner.RunDaily(context.Background(), "2024-05-18")
+	require.Error(t, err)
+	require.Len(t, *delays, 2)
+	require.Equal(t, model.JobStatusFailed, jobService.runs[0].Status)
+	require.Equal(t, 1, jobService.runs[0].Attempts)
+}
+
+func TestRunner_RunScheduledOncePerDay(t *testing.T) {
+	t.Parallel()
+
+	fetches := 0
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			fetches++
+			return &model.Record{Date: "2024-05-18"}, nil
+		},
+		BackfillFunc: func(startDate, endDate string) (int, error) {
+			return 1, nil
+		},
+	}
+	jobService := &mockJobService{succeeded: map[string]bool{"daily apod 2024-05-17": true}}
+	runner, _ := newTestRunner(fetcher, jobService)
+
+	runner.runScheduled(context.Background())
+	require.Equal(t, 1, fetches)
+
+	jobService.succeeded["daily apod 2024-05-18"] = true
+	runner.runScheduled(context.Background())
+	require.Equal(t, 1, fetches)
+}
+
+func TestRunner_Due(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := ParseSchedule("30 5 * * *", apodLocation)
+	require.NoError(t, err)
+
+	runner, _ := newTestRunner(&mockFetcher{}, &mockJobService{})
+
+	runner.now = func() time.Time { return time.Date(2024, 5, 18, 5, 29, 0, 0, apodLocation) }
+	require.False(t, runner.due(schedule))
+
+	runner.now = func() time.Time { return time.Date(2024, 5, 18, 5, 30, 0, 0, apodLocation) }
+	require.True(t, runner.due(schedule))
+
+	runner.now = func() time.Time { return time.Date(2024, 5, 18, 23, 0, 0, 0, apodLocation) }
+	require.True(t, runner.due(schedule))
+}
+
+func TestRunner_StartWaitsByRunnerClock(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := ParseSchedule("30 5 * * *", apodLocation)
+	require.NoError(t, err)
+
+	fetches := 0
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			fetches++
+			return &model.Record{Date: "2024-05-18"}, nil
+		},
+	}
+	runner, _ := newTestRunner(fetcher, &mockJobService{})
+	// The next run is half an hour away by the clock of the runner, even though that time has long passed.
+	runner.now = func() time.Time { return time.Date(2024, 5, 18, 5, 0, 0, 0, apodLocation) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	runner.Start(ctx, schedule, 0)
+
+	require.Zero(t, fetches)
+}
+
+func TestAPODDate(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "2024-05-17", APODDate(time.Date(2024, 5, 18, 3, 59, 0, 0, time.UTC)))
+	require.Equal(t, "2024-05-18", APODDate(time.Date(2024, 5, 18, 4, 0, 0, 0, time.UTC)))
+}
+
+func TestAPODDayStart(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 5, 18, 4, 0, 0, 0, time.UTC)
+	require.True(t, start.Equal(APODDayStart(time.Date(2024, 5, 18, 4, 0, 0, 0, time.UTC))))
+	require.True(t, start.Equal(APODDayStart(time.Date(2024, 5, 19, 3, 59, 0, 0, time.UTC))))
+}
+
+func TestRunner_RunCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fetcher := &mockFetcher{
+		FetchLatestFunc: func() (*model.Record, error) {
+			cancel()
+			return nil, errors.New("connection reset")
+		},
+	}
+	jobService := &mockJobService{}
+	runner, delays := newTestRunner(fetcher, jobService)
+
+	_, err := runner.RunDaily(ctx, "2024-05-18")
+	require.Error(t, err)
+	require.Empty(t, *delays)
+	require.Len(t, jobService.runs, 1)
+	require.Equal(t, 1, jobService.runs[0].Attempts)
+	require.Equal(t, model.JobStatusFailed, jobService.runs[0].Status)
+}
+
+func TestSleep(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, sleep(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, sleep(ctx, time.Hour), context.Canceled)
+}
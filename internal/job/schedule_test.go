@@ -0,0 +1,104 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"30 5 * * *", "*/15 * * * *", "0 9-17/2 * * 1-5", "0 0 1,15 * *", "0 0 * * 7", "@daily", "@hourly"} {
+		_, err := ParseSchedule(expr, time.UTC)
+		require.NoError(t, err, expr)
+	}
+
+	for _, expr := range []string{"", "30 5 * *", "60 5 * * *", "30 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "0 5-3 * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := ParseSchedule(expr, time.UTC)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		expr     string
+		location *time.Location
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "SameDay",
+			expr:     "30 5 * * *",
+			location: newYork,
+			after:    time.Date(2024, 5, 18, 1, 0, 0, 0, newYork),
+			expected: time.Date(2024, 5, 18, 5, 30, 0, 0, newYork),
+		},
+		{
+			name:     "NextDay",
+			expr:     "30 5 * * *",
+			location: newYork,
+			after:    time.Date(2024, 5, 18, 5, 30, 0, 0, newYork),
+			expected: time.Date(2024, 5, 19, 5, 30, 0, 0, newYork),
+		},
+		{
+			name:     "EvaluatedInLocation",
+			expr:     "30 5 * * *",
+			location: newYork,
+			after:    time.Date(2024, 5, 18, 8, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 18, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "AcrossDST",
+			expr:     "30 2 * * *",
+			location: newYork,
+			after:    time.Date(2024, 3, 9, 3, 0, 0, 0, newYork),
+			expected: time.Date(2024, 3, 11, 2, 30, 0, 0, newYork),
+		},
+		{
+			name:     "Step",
+			expr:     "*/15 * * * *",
+			location: time.UTC,
+			after:    time.Date(2024, 5, 18, 10, 16, 30, 0, time.UTC),
+			expected: time.Date(2024, 5, 18, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Weekdays",
+			expr:     "0 9 * * 1-5",
+			location: time.UTC,
+			after:    time.Date(2024, 5, 17, 10, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "DayOfMonthOrWeek",
+			expr:     "0 0 1 * 0",
+			location: time.UTC,
+			after:    time.Date(2024, 5, 18, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "LeapDay",
+			expr:     "0 0 29 2 *",
+			location: time.UTC,
+			after:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr, tt.location)
+			require.NoError(t, err)
+
+			next := schedule.Next(tt.after)
+			require.True(t, tt.expected.Equal(next), "expected %s, got %s", tt.expected, next)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+// Package logging carries the fields describing the request or job being processed through contexts,
+// so that every log entry written on its behalf can be correlated.
+package logging
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type entryKey struct{}
+
+// WithFields returns a copy of ctx whose logger adds the fields to every entry, in addition to the fields
+// already carried by ctx.
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	return context.WithValue(ctx, entryKey{}, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext returns the logger of the context, which writes the fields added with WithFields
+// through the standard logger.
+func FromContext(ctx context.Context) *log.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*log.Entry); ok {
+		return entry
+	}
+	return log.NewEntry(log.StandardLogger())
+}
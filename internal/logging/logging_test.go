@@ -0,0 +1,20 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, FromContext(context.Background()).Data)
+
+	ctx := WithFields(context.Background(), log.Fields{"request_id": "abc", "path": "/images"})
+	ctx = WithFields(ctx, log.Fields{"path": "/images/2024-05-18", "source": "apod"})
+
+	require.Equal(t, log.Fields{"request_id": "abc", "path": "/images/2024-05-18", "source": "apod"}, FromContext(ctx).Data)
+}
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/httpserver"
+)
+
+// Instrument wraps next so that the duration and status of every request are observed by the route it
+// matches, which must have been resolved by httpserver.WithRoute.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := httpserver.NewResponseRecorder(w)
+		next.ServeHTTP(recorder, r)
+
+		HTTPRequestDuration.WithLabelValues(r.Method, httpserver.Route(r), strconv.Itoa(recorder.Status())).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,148 @@
+// Package metrics defines the Prometheus metrics of the application and serves them.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+const namespace = "youngastrologer"
+
+// Registry holds the metrics of the application along with the Go runtime and process metrics.
+var Registry = newRegistry()
+
+func newRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return registry
+}
+
+var factory = promauto.With(Registry)
+
+var (
+	// HTTPRequestDuration observes the duration of the served HTTP requests by method, route pattern and status code.
+	// Its count is the number of served requests.
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the served HTTP requests by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPRateLimited counts the requests rejected because their client exceeded its rate limit, by route pattern.
+	HTTPRateLimited = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests rejected because their client exceeded its rate limit, by route.",
+	}, []string{"route"})
+
+	// JobRuns counts the finished fetch job runs by job, source and status.
+	JobRuns = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "runs_total",
+		Help:      "Number of finished fetch job runs by job, source and status.",
+	}, []string{"job", "source", "status"})
+
+	// JobDuration observes the duration of the fetch job runs, retries included, by job, source and status.
+	JobDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "duration_seconds",
+		Help:      "Duration of the fetch job runs, retries included, by job, source and status.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200},
+	}, []string{"job", "source", "status"})
+
+	// NASAResponses counts the responses of the NASA API by source and status code.
+	NASAResponses = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "nasa",
+		Name:      "responses_total",
+		Help:      "Number of responses of the NASA API by source and status code.",
+	}, []string{"source", "status"})
+
+	// NASARateLimitRemaining is the number of requests left to the API key, as last reported by the NASA API.
+	NASARateLimitRemaining = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "nasa",
+		Name:      "rate_limit_remaining",
+		Help:      "Number of requests left to the API key as reported by the X-RateLimit-Remaining header of the NASA API.",
+	}, []string{"source"})
+
+	// ImageDownloadBytes observes the size of the downloaded images by source and rendition: standard or hd.
+	ImageDownloadBytes = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "download",
+		Name:      "image_bytes",
+		Help:      "Size of the downloaded images by source and rendition.",
+		Buckets:   prometheus.ExponentialBuckets(16<<10, 4, 8),
+	}, []string{"source", "rendition"})
+
+	// DBQueryDuration observes the duration of the database queries by ImageManager method.
+	DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of the database queries by ImageManager method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// statsTimeout bounds how long collecting the storage statistics may delay a scrape.
+const statsTimeout = 5 * time.Second
+
+// StatsFunc returns the statistics of the stored images.
+type StatsFunc func(ctx context.Context) (*model.StorageStats, error)
+
+// RegisterStorage registers the metrics of the stored images, which are collected with stats on every scrape.
+func RegisterStorage(stats StatsFunc) {
+	Registry.MustRegister(&storageCollector{
+		stats: stats,
+		images: prometheus.NewDesc(prometheus.BuildFQName(namespace, "storage", "images"),
+			"Number of stored images.", nil, nil),
+		bytes: prometheus.NewDesc(prometheus.BuildFQName(namespace, "storage", "bytes"),
+			"Size of the stored image data in bytes, counting data shared by several images once.", nil, nil),
+	})
+}
+
+type storageCollector struct {
+	stats  StatsFunc
+	images *prometheus.Desc
+	bytes  *prometheus.Desc
+}
+
+// Describe sends the descriptors of the storage metrics.
+func (sc *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sc.images
+	ch <- sc.bytes
+}
+
+// Collect collects the storage statistics and sends them as metrics. Nothing is sent if they cannot be collected.
+func (sc *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := sc.stats(ctx)
+	if err != nil {
+		log.Errorf("Error collecting storage statistics: %v", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(sc.images, prometheus.GaugeValue, float64(stats.Images))
+	ch <- prometheus.MustNewConstMetric(sc.bytes, prometheus.GaugeValue, float64(stats.Bytes))
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/httpserver"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+func TestInstrument(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /test/{date}/raw", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("date") == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	handler := httpserver.WithRoute(mux, Instrument(mux))
+
+	tests := []struct {
+		name   string
+		path   string
+		route  string
+		status string
+		count  int
+	}{
+		{name: "Matched", path: "/test/2024-05-18/raw", route: "GET /test/{date}/raw", status: "200", count: 2},
+		{name: "StatusWritten", path: "/test/missing/raw", route: "GET /test/{date}/raw", status: "404", count: 1},
+		{name: "Unmatched", path: "/unknown", route: httpserver.UnmatchedRoute, status: "404", count: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.count; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			}
+
+			var metric dto.Metric
+			histogram := HTTPRequestDuration.WithLabelValues(http.MethodGet, tt.route, tt.status).(prometheus.Metric)
+			require.NoError(t, histogram.Write(&metric))
+			require.Equal(t, uint64(tt.count), metric.GetHistogram().GetSampleCount())
+		})
+	}
+}
+
+func TestStorageCollector(t *testing.T) {
+	collector := &storageCollector{
+		stats: func(ctx context.Context) (*model.StorageStats, error) {
+			return &model.StorageStats{Images: 3, Bytes: 2048}, nil
+		},
+		images: prometheus.NewDesc("test_storage_images", "Number of stored images.", nil, nil),
+		bytes:  prometheus.NewDesc("test_storage_bytes", "Size of the stored image data in bytes.", nil, nil),
+	}
+
+	expected := `
+# HELP test_storage_bytes Size of the stored image data in bytes.
+# TYPE test_storage_bytes gauge
+test_storage_bytes 2048
+# HELP test_storage_images Number of stored images.
+# TYPE test_storage_images gauge
+test_storage_images 3
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	collector.stats = func(ctx context.Context) (*model.StorageStats, error) {
+		return nil, errors.New("connection refused")
+	}
+	require.Zero(t, testutil.CollectAndCount(collector))
+}
+
+func TestHandler(t *testing.T) {
+	JobRuns.WithLabelValues("daily", "apod", "success").Inc()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `youngastrologer_job_runs_total{job="daily",source="apod",status="success"}`)
+	require.Contains(t, w.Body.String(), "go_goroutines")
+}
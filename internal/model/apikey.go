@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles of API keys. An admin key is granted everything a read key is.
+const (
+	RoleRead  = "read"
+	RoleAdmin = "admin"
+)
+
+// APIKey represents a key clients authenticate with. Only the hash of the secret is stored.
+type APIKey struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Prefix is the beginning of the secret, which identifies the key without revealing it.
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	Role       string     `json:"role"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Grants reports whether the role of the key includes the provided role.
+func (k *APIKey) Grants(role string) bool {
+	return k.Role == RoleAdmin || k.Role == role
+}
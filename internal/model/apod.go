@@ -1,10 +1,20 @@
 package model
 
-// APOD represents information about an Astronomy Picture of the Day.
+// Media types of APOD entries.
+const (
+	MediaTypeImage = "image"
+	MediaTypeVideo = "video"
+)
+
+// APOD represents an Astronomy Picture of the Day as returned by the NASA APOD API.
 type APOD struct {
-	Date        string `json:"date"`
-	Explanation string `json:"explanation"`
-	URL         string `json:"url"`
-	Title       string `json:"title"`
-	MediaType   string `json:"media_type"`
+	Date           string `json:"date"`
+	Explanation    string `json:"explanation"`
+	URL            string `json:"url"`
+	HDURL          string `json:"hdurl"`
+	Title          string `json:"title"`
+	MediaType      string `json:"media_type"`
+	ThumbnailURL   string `json:"thumbnail_url"`
+	Copyright      string `json:"copyright"`
+	ServiceVersion string `json:"service_version"`
 }
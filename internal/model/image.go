@@ -5,12 +5,112 @@ import (
 	"github.com/google/uuid"
 )
 
-// Image represents an image entity
+// Image represents an image entity. Its date is unique among the images of its source.
 type Image struct {
 	ID          uuid.UUID
+	Source      string
 	Date        string
 	Explanation string
 	MediaType   string
 	Title       string
+	// URL is the source URL of the image, or the embed URL of a video.
+	URL string
+	// HDURL is the source URL of the high definition rendition of the image.
+	HDURL          string
+	Copyright      string
+	ServiceVersion string
+	// Video is set for video entries only; Data then holds the downloaded thumbnail, if any.
+	Video *Video
+	Data  []byte
+	// HDData holds the downloaded high definition rendition, if any. It is loaded separately from Data.
+	HDData []byte `json:"-"`
+	// Size and HDSize are the sizes of Data and HDData in bytes.
+	Size   int64
+	HDSize int64
+	// BlobKey and HDBlobKey reference Data and HDData in the blob store. They are empty
+	// when the data is kept in the database.
+	BlobKey   string `json:"-"`
+	HDBlobKey string `json:"-"`
+	// HDChecksum is the hex-encoded SHA-256 checksum of HDData. Data and HDData are stored once
+	// per checksum and shared by all images with the same picture.
+	HDChecksum string `json:"-"`
+	// ImageInfo describes Data. It is empty for images saved before it was recorded.
+	ImageInfo
+	// Renditions are the downscaled copies of Data. They are only set when an image is saved.
+	Renditions []*Rendition `json:"-"`
+}
+
+// Blob is the data of a picture stored once and shared by all images with the same checksum.
+type Blob struct {
+	// Checksum is the hex-encoded SHA-256 checksum of the data.
+	Checksum string
+	// Data is empty when the data is kept in the blob store.
+	Data []byte
+	// BlobKey references the data in the blob store. It is empty when the data is kept in the database.
+	BlobKey string
+	// Size is the size of the data in bytes.
+	Size int64
+}
+
+// ImageRef identifies an image by its source and date.
+type ImageRef struct {
+	Source string `json:"source"`
+	Date   string `json:"date"`
+}
+
+// Video represents the video-specific details of an APOD entry.
+type Video struct {
+	Provider     string `json:"provider"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// Names of the downscaled renditions of an image.
+const (
+	RenditionThumb  = "thumb"
+	RenditionMedium = "medium"
+)
+
+// Rendition is a downscaled copy of the data of an image.
+type Rendition struct {
+	Name        string
+	ContentType string
+	Width       int
+	Height      int
 	Data        []byte
+	// Size is the size of Data in bytes.
+	Size int64
+	// BlobKey references Data in the blob store. It is empty when the data is kept in the database.
+	BlobKey string
+}
+
+// EXIFTimeLayout is the layout of the capture time of an image. EXIF times carry no time zone.
+const EXIFTimeLayout = "2006-01-02T15:04:05"
+
+// ImageInfo describes the stored data of an image.
+type ImageInfo struct {
+	// Format is the name of the decoded format: jpeg, png or gif. It is empty if the data could not be decoded.
+	Format string `json:"format,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	// Checksum is the hex-encoded SHA-256 checksum of the data.
+	Checksum string `json:"checksum,omitempty"`
+	// DominantColor is the most common color of the image in the #rrggbb form.
+	DominantColor string `json:"dominant_color,omitempty"`
+	EXIF          *EXIF  `json:"exif,omitempty"`
+	// SameAs lists the other images with the same checksum, that is the same picture, ordered by date.
+	SameAs []ImageRef `json:"same_as,omitempty"`
+}
+
+// EXIF contains the camera, exposure and capture time fields recorded in an image.
+type EXIF struct {
+	CameraMake  string `json:"camera_make,omitempty"`
+	CameraModel string `json:"camera_model,omitempty"`
+	// ExposureTime is the exposure time in seconds, such as 1/250 or 30.
+	ExposureTime string  `json:"exposure_time,omitempty"`
+	FNumber      float64 `json:"f_number,omitempty"`
+	ISO          int     `json:"iso,omitempty"`
+	// FocalLength is the focal length in millimeters.
+	FocalLength float64 `json:"focal_length,omitempty"`
+	// CapturedAt is the local time the image was captured in the EXIFTimeLayout.
+	CapturedAt string `json:"captured_at,omitempty"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of job runs.
+const (
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+)
+
+// JobRun represents the outcome of a single run of a background job.
+type JobRun struct {
+	ID  uuid.UUID `json:"id"`
+	Job string    `json:"job"`
+	// Source is the name of the source the run fetched from.
+	Source string `json:"source"`
+	// APODDate is the APOD day a scheduled daily run was responsible for.
+	APODDate   string    `json:"apod_date,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	Saved      int       `json:"saved"`
+	Error      string    `json:"error,omitempty"`
+}
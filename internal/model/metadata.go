@@ -0,0 +1,97 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// SortOrder defines the order in which images are listed by date.
+type SortOrder string
+
+// Supported sort orders.
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// ImageMetadata represents an image entity without its binary data.
+type ImageMetadata struct {
+	ID             uuid.UUID `json:"id"`
+	Source         string    `json:"source"`
+	Date           string    `json:"date"`
+	Title          string    `json:"title"`
+	MediaType      string    `json:"media_type"`
+	Explanation    string    `json:"explanation"`
+	URL            string    `json:"url,omitempty"`
+	HDURL          string    `json:"hdurl,omitempty"`
+	Copyright      string    `json:"copyright,omitempty"`
+	ServiceVersion string    `json:"service_version,omitempty"`
+	Video          *Video    `json:"video,omitempty"`
+	Size           int64     `json:"size"`
+	HDSize         int64     `json:"hd_size,omitempty"`
+	ImageInfo
+}
+
+// Orientation classifies images by the ratio of their width to their height.
+type Orientation string
+
+// Supported orientations.
+const (
+	OrientationLandscape Orientation = "landscape"
+	OrientationPortrait  Orientation = "portrait"
+	OrientationSquare    Orientation = "square"
+)
+
+// ListOptions defines pagination, filtering and sorting options for listing images.
+type ListOptions struct {
+	// Source restricts the listing to the images of a single source; empty lists all sources.
+	Source string
+	// Limit is the maximum number of images to return.
+	Limit int
+	// After is the cursor: only images dated after it (or before it in descending order) are returned.
+	After string
+	// From and To restrict the listing to the inclusive date range; empty values are unbounded.
+	From string
+	To   string
+	// Order is the sort order by date.
+	Order SortOrder
+	// Orientation restricts the listing to images of the orientation; empty lists all orientations.
+	Orientation Orientation
+	// MinWidth and MinHeight restrict the listing to images of at least the dimensions in pixels; zero values are unbounded.
+	// Images whose dimensions are unknown are excluded by both filters.
+	MinWidth  int
+	MinHeight int
+}
+
+// SearchOptions defines the query and pagination options for searching images.
+type SearchOptions struct {
+	// Source restricts the search to the images of a single source; empty searches all sources.
+	Source string
+	// Query is the search text in web search syntax: quoted phrases, "or" and "-" to exclude words.
+	Query string
+	// Limit is the maximum number of results to return.
+	Limit int
+	// Offset is the number of best ranked results to skip.
+	Offset int
+}
+
+// Highlight contains excerpts of an image with the matched words enclosed in <mark> tags.
+// The excerpts are HTML: the stored text is escaped, so the <mark> tags are the only markup in them.
+type Highlight struct {
+	Title       string `json:"title"`
+	Explanation string `json:"explanation"`
+}
+
+// SearchResult is an image matching a search, with its relevance and highlighted excerpts.
+type SearchResult struct {
+	*ImageMetadata
+	Rank      float64   `json:"rank"`
+	Highlight Highlight `json:"highlight"`
+}
+
+// StorageStats summarizes the stored images.
+type StorageStats struct {
+	// Images is the number of stored images.
+	Images int64
+	// Bytes is the size of the stored image data, counting data shared by several images once.
+	Bytes int64
+}
@@ -0,0 +1,21 @@
+package model
+
+// DefaultSource is the name of the NASA Astronomy Picture of the Day source.
+const DefaultSource = "apod"
+
+// Record represents a daily picture entry normalized from an upstream source.
+type Record struct {
+	// Source is the name of the source that published the record.
+	Source      string `json:"-"`
+	Date        string `json:"date"`
+	Title       string `json:"title"`
+	Explanation string `json:"explanation"`
+	MediaType   string `json:"media_type"`
+	// URL is the location of the picture, or the embed URL of a video.
+	URL          string `json:"url"`
+	HDURL        string `json:"hdurl"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Copyright    string `json:"copyright"`
+	// ServiceVersion is the version of the upstream API, if it reports one.
+	ServiceVersion string `json:"service_version"`
+}
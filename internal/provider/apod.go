@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/metrics"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+// APODBaseURL is the URL of NASA's public APOD API.
+const APODBaseURL = "https://api.nasa.gov/planetary/apod"
+
+// NewAPOD returns a Provider that fetches the Astronomy Picture of the Day from the NASA APOD API at baseURL,
+// or at APODBaseURL if baseURL is empty.
+func NewAPOD(name string, client *http.Client, baseURL, apiKey string) Provider {
+	if baseURL == "" {
+		baseURL = APODBaseURL
+	}
+
+	return &apodProvider{
+		name:    name,
+		client:  client,
+		baseURL: baseURL,
+		apiKey:  apiKey,
+	}
+}
+
+type apodProvider struct {
+	name    string
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+// Name returns the name of the source.
+func (ap *apodProvider) Name() string {
+	return ap.name
+}
+
+// Latest fetches the current APOD entry.
+func (ap *apodProvider) Latest(ctx context.Context) (*model.Record, error) {
+	query := url.Values{}
+	query.Set("api_key", ap.apiKey)
+	query.Set("thumbs", "true")
+
+	var apodResponse model.APOD
+	if err := ap.fetchJSON(ctx, query, &apodResponse); err != nil {
+		return nil, err
+	}
+
+	return ap.toRecord(&apodResponse), nil
+}
+
+// Range fetches the APOD entries of every day between startDate and endDate (inclusive).
+func (ap *apodProvider) Range(ctx context.Context, startDate, endDate string) ([]*model.Record, error) {
+	query := url.Values{}
+	query.Set("api_key", ap.apiKey)
+	query.Set("thumbs", "true")
+	query.Set("start_date", startDate)
+	query.Set("end_date", endDate)
+
+	var apodResponse []*model.APOD
+	if err := ap.fetchJSON(ctx, query, &apodResponse); err != nil {
+		return nil, err
+	}
+
+	records := make([]*model.Record, 0, len(apodResponse))
+	for _, apod := range apodResponse {
+		records = append(records, ap.toRecord(apod))
+	}
+	return records, nil
+}
+
+// Download fetches the content of the provided URL.
+func (ap *apodProvider) Download(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ap.client.Do(req)
+	if err != nil {
+		err = RedactError(err)
+		logging.FromContext(ctx).Errorf("Error fetching image: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logging.FromContext(ctx).Errorf("Unexpected status code: %d", resp.StatusCode)
+		return nil, newStatusError(resp)
+	}
+
+	imgData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Error reading image data: %v", err)
+		return nil, err
+	}
+
+	return imgData, nil
+}
+
+// fetchJSON requests the APOD API with the given query parameters and decodes the response into v.
+func (ap *apodProvider) fetchJSON(ctx context.Context, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ap.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := ap.client.Do(req)
+	if err != nil {
+		err = RedactError(err)
+		logging.FromContext(ctx).Errorf("Error fetching APOD from NASA API: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+	ap.observe(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		logging.FromContext(ctx).Errorf("Unexpected status code: %d", resp.StatusCode)
+		return newStatusError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Error reading response body: %v", err)
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		logging.FromContext(ctx).Errorf("Error unmarshalling response: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// observe records the status code and the remaining rate limit reported by an API response.
+func (ap *apodProvider) observe(resp *http.Response) {
+	metrics.NASAResponses.WithLabelValues(ap.name, strconv.Itoa(resp.StatusCode)).Inc()
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		metrics.NASARateLimitRemaining.WithLabelValues(ap.name).Set(float64(remaining))
+	}
+}
+
+// toRecord normalizes an APOD entry.
+func (ap *apodProvider) toRecord(apod *model.APOD) *model.Record {
+	return &model.Record{
+		Source:         ap.name,
+		Date:           apod.Date,
+		Title:          apod.Title,
+		Explanation:    apod.Explanation,
+		MediaType:      apod.MediaType,
+		URL:            apod.URL,
+		HDURL:          apod.HDURL,
+		ThumbnailURL:   apod.ThumbnailURL,
+		Copyright:      apod.Copyright,
+		ServiceVersion: apod.ServiceVersion,
+	}
+}
@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+const dateLayout = "2006-01-02"
+
+// errNoRecords is returned when a directory does not contain any record.
+var errNoRecords = errors.New("no records found")
+
+// NewDirectory returns a Provider that reads records dropped into a local directory.
+// Every record is a YYYY-MM-DD.json file holding a model.Record, whose URLs are paths
+// relative to the directory. The date in the file name takes precedence over the date in the file.
+func NewDirectory(name, dir string) Provider {
+	return &directoryProvider{
+		name: name,
+		dir:  dir,
+	}
+}
+
+type directoryProvider struct {
+	name string
+	dir  string
+}
+
+// Name returns the name of the source.
+func (dp *directoryProvider) Name() string {
+	return dp.name
+}
+
+// Latest returns the record with the most recent date.
+func (dp *directoryProvider) Latest(ctx context.Context) (*model.Record, error) {
+	dates, err := dp.dates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("%s: %w", dp.dir, errNoRecords)
+	}
+
+	return dp.read(dates[len(dates)-1])
+}
+
+// Range returns the records dated between startDate and endDate (inclusive).
+func (dp *directoryProvider) Range(ctx context.Context, startDate, endDate string) ([]*model.Record, error) {
+	dates, err := dp.dates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*model.Record
+	for _, date := range dates {
+		if date < startDate || date > endDate {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		record, err := dp.read(date)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Download reads the file at the provided path relative to the directory.
+// Paths leading outside of the directory are rejected.
+func (dp *directoryProvider) Download(ctx context.Context, rawURL string) ([]byte, error) {
+	path := filepath.FromSlash(rawURL)
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("path %q is outside of %s", rawURL, dp.dir)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dp.dir, path))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Error reading image: %v", err)
+		return nil, err
+	}
+	return data, nil
+}
+
+// dates returns the dates of the records in the directory in chronological order.
+func (dp *directoryProvider) dates(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(dp.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []string
+	for _, entry := range entries {
+		date, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			logging.FromContext(ctx).Warnf("Skipping %s: file name is not a date", filepath.Join(dp.dir, entry.Name()))
+			continue
+		}
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// read reads the record of the provided date.
+func (dp *directoryProvider) read(date string) (*model.Record, error) {
+	data, err := os.ReadFile(filepath.Join(dp.dir, date+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var record model.Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parse record for date %s: %w", date, err)
+	}
+	record.Source = dp.name
+	record.Date = date
+	return &record, nil
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+func writeDropFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeDropFile(t, dir, "2024-05-18.json", `{"title": "Observatory Sky", "media_type": "image", "url": "images/sky.jpg"}`)
+	writeDropFile(t, dir, "2024-05-20.json", `{"date": "1999-01-01", "title": "Moon", "media_type": "image", "url": "moon.jpg"}`)
+	writeDropFile(t, dir, "2024-05-19.json", `{"title": "Planets", "media_type": "image", "url": "planets.jpg"}`)
+	writeDropFile(t, dir, "notes.json", `{}`)
+	writeDropFile(t, dir, "images/sky.jpg", "\xFF\xD8\xFF")
+
+	dirProvider := NewDirectory("drop", dir)
+	require.Equal(t, "drop", dirProvider.Name())
+
+	record, err := dirProvider.Latest(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &model.Record{Source: "drop", Date: "2024-05-20", Title: "Moon", MediaType: "image", URL: "moon.jpg"}, record)
+
+	records, err := dirProvider.Range(context.Background(), "2024-05-01", "2024-05-19")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "2024-05-18", records[0].Date)
+	require.Equal(t, "2024-05-19", records[1].Date)
+
+	data, err := dirProvider.Download(context.Background(), records[0].URL)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\xFF\xD8\xFF"), data)
+}
+
+func TestDirectory_Errors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	dirProvider := NewDirectory("drop", dir)
+
+	_, err := dirProvider.Latest(context.Background())
+	require.ErrorIs(t, err, errNoRecords)
+
+	for _, path := range []string{"../secret.jpg", "/etc/passwd", "images/../../secret.jpg"} {
+		_, err = dirProvider.Download(context.Background(), path)
+		require.ErrorContains(t, err, "is outside of", path)
+	}
+
+	writeDropFile(t, dir, "2024-05-18.json", `{"title": `)
+	_, err = dirProvider.Range(context.Background(), "2024-05-18", "2024-05-18")
+	require.ErrorContains(t, err, "parse record for date 2024-05-18")
+
+	_, err = NewDirectory("drop", filepath.Join(dir, "missing")).Latest(context.Background())
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,108 @@
+// Package provider defines the upstream sources of daily pictures and their implementations.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+// Provider fetches daily picture records from an upstream source.
+type Provider interface {
+	// Name returns the name of the source, which is stored with every image fetched from it.
+	Name() string
+	// Latest returns the most recently published record.
+	Latest(ctx context.Context) (*model.Record, error)
+	// Range returns the records published between startDate and endDate (inclusive).
+	Range(ctx context.Context, startDate, endDate string) ([]*model.Record, error)
+	// Download returns the content referenced by a URL of a record.
+	Download(ctx context.Context, rawURL string) ([]byte, error)
+}
+
+// StatusError is returned when an upstream server responds with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	retryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// RetryAfter returns the delay requested by the server through the Retry-After header, if any.
+func (e *StatusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// Retryable reports whether the request may succeed when retried.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// newStatusError creates a StatusError from an unexpected response.
+func newStatusError(resp *http.Response) *StatusError {
+	statusErr := &StatusError{StatusCode: resp.StatusCode}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+			statusErr.retryAfter = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			statusErr.retryAfter = time.Until(at)
+		}
+	}
+
+	return statusErr
+}
+
+// redactedError is an error whose message has the query removed from the URL of a request.
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError returns err with the query and credentials removed from the URL of the request that
+// failed with it, if any. The query of an APOD API request holds the API key, so errors must be redacted
+// before they are logged or stored.
+func RedactError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	redactedURL := redactURL(urlErr.URL)
+	if redactedURL == urlErr.URL {
+		return err
+	}
+	if err == error(urlErr) {
+		return &url.Error{Op: urlErr.Op, URL: redactedURL, Err: urlErr.Err}
+	}
+	return &redactedError{err: err, message: strings.ReplaceAll(err.Error(), urlErr.URL, redactedURL)}
+}
+
+// redactURL returns rawURL without its query, fragment and user information.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
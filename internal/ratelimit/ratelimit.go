@@ -0,0 +1,143 @@
+// Package ratelimit limits the rate of requests with token buckets: every key has a bucket holding up to
+// a burst of tokens, which is refilled at a steady rate, and a request is allowed if it can take a token.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the in-memory limiter drops the buckets that have been refilled completely.
+const sweepInterval = time.Minute
+
+// Limit is the rate at which a bucket is refilled and its capacity.
+type Limit struct {
+	// Rate is the number of tokens added to the bucket per second.
+	Rate float64
+	// Burst is the number of tokens the bucket holds when full.
+	Burst int
+	// Quota and Window describe the rate to clients: Quota requests are allowed per Window.
+	Quota  int
+	Window time.Duration
+}
+
+// PerPeriod returns the limit allowing requests per period on average, in bursts of up to burst requests.
+func PerPeriod(requests int, period time.Duration, burst int) Limit {
+	return Limit{Rate: float64(requests) / period.Seconds(), Burst: burst, Quota: requests, Window: period}
+}
+
+// durationFor returns how long refilling the bucket with tokens takes.
+func (l Limit) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.Rate * float64(time.Second))
+}
+
+// Result is the outcome of a request for a token.
+type Result struct {
+	// Allowed reports whether a token was taken.
+	Allowed bool
+	// Limit is the capacity of the bucket.
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long it takes until a token is available; zero if one was taken.
+	RetryAfter time.Duration
+	// Reset is how long it takes until the bucket is full again.
+	Reset time.Duration
+}
+
+// Limiter hands out the tokens of the buckets of keys.
+type Limiter interface {
+	// Allow takes a token from the bucket of the key, which is created full and refilled according to limit.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Bucket is the state of a token bucket. It is not safe for concurrent use.
+type Bucket struct {
+	// Tokens is the number of tokens, including fractions, in the bucket when it was last updated.
+	Tokens float64
+	// Updated is when the bucket was last updated.
+	Updated time.Time
+}
+
+// NewBucket returns a full bucket.
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), Updated: now}
+}
+
+// Take refills the bucket for the time passed since it was last updated and takes a token if there is one.
+// Times before the last update, which are seen if the clocks of replicas sharing the bucket drift, add
+// no tokens.
+func (b *Bucket) Take(now time.Time, limit Limit) Result {
+	if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed.Seconds()*limit.Rate)
+		b.Updated = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = limit.durationFor(1 - b.Tokens)
+	}
+	result.Remaining = int(b.Tokens)
+	result.Reset = limit.durationFor(float64(limit.Burst) - b.Tokens)
+	return result
+}
+
+// FullAt returns when the bucket is full again, after which it is equivalent to a new bucket.
+func (b *Bucket) FullAt(limit Limit) time.Time {
+	return b.Updated.Add(limit.durationFor(float64(limit.Burst) - b.Tokens))
+}
+
+// NewMemoryLimiter returns a Limiter that keeps the buckets in memory, so every replica of the service
+// limits the requests it serves on its own.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+}
+
+type memoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	fullAt time.Time
+}
+
+// Allow takes a token from the bucket of the key. Buckets that have been refilled completely are dropped
+// once a minute, so idle clients take no memory.
+func (ml *memoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := ml.now()
+	if now.Sub(ml.lastSweep) >= sweepInterval {
+		for k, bucket := range ml.buckets {
+			if !bucket.fullAt.After(now) {
+				delete(ml.buckets, k)
+			}
+		}
+		ml.lastSweep = now
+	}
+
+	bucket, ok := ml.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{Bucket: NewBucket(limit, now)}
+		ml.buckets[key] = bucket
+	}
+	result := bucket.Take(now, limit)
+	bucket.fullAt = bucket.FullAt(limit)
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_Take(t *testing.T) {
+	t.Parallel()
+
+	limit := PerPeriod(60, time.Minute, 2)
+	start := time.Date(2024, 5, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		bucket   Bucket
+		now      time.Time
+		expected Result
+	}{
+		{
+			name:     "Full",
+			bucket:   NewBucket(limit, start),
+			now:      start,
+			expected: Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second},
+		},
+		{
+			name:     "LastToken",
+			bucket:   Bucket{Tokens: 1, Updated: start},
+			now:      start,
+			expected: Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second},
+		},
+		{
+			name:     "Empty",
+			bucket:   Bucket{Tokens: 0.25, Updated: start},
+			now:      start,
+			expected: Result{Limit: 2, Remaining: 0, RetryAfter: 750 * time.Millisecond, Reset: 1750 * time.Millisecond},
+		},
+		{
+			name:     "Refilled",
+			bucket:   Bucket{Tokens: 0, Updated: start},
+			now:      start.Add(1500 * time.Millisecond),
+			expected: Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 1500 * time.Millisecond},
+		},
+		{
+			name:     "RefilledBeyondBurst",
+			bucket:   Bucket{Tokens: 0, Updated: start},
+			now:      start.Add(time.Hour),
+			expected: Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second},
+		},
+		{
+			name:     "ClockBehind",
+			bucket:   Bucket{Tokens: 0.5, Updated: start},
+			now:      start.Add(-time.Minute),
+			expected: Result{Limit: 2, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 1500 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bucket := tt.bucket
+			require.Equal(t, tt.expected, bucket.Take(tt.now, limit))
+			require.Equal(t, bucket.Updated.Add(tt.expected.Reset), bucket.FullAt(limit))
+		})
+	}
+}
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 18, 12, 0, 0, 0, time.UTC)
+	limiter := &memoryLimiter{
+		buckets: make(map[string]*memoryBucket),
+		now:     func() time.Time { return now },
+	}
+	limit := PerPeriod(1, time.Second, 3)
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(context.Background(), "client", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 2-i, result.Remaining)
+	}
+	result, err := limiter.Allow(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	result, err = limiter.Allow(context.Background(), "other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	now = now.Add(2 * time.Second)
+	result, err = limiter.Allow(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+
+	// Once refilled completely, idle buckets are dropped.
+	now = now.Add(sweepInterval)
+	_, err = limiter.Allow(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+}
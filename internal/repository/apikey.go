@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+// APIKeyManager defines the interface for managing API keys.
+type APIKeyManager interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	List(ctx context.Context) ([]*model.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	SetLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// NewAPIKeyManager returns a new instance of APIKeyManager.
+func NewAPIKeyManager(db *sql.DB) APIKeyManager {
+	return &apiKeyManager{
+		db: db,
+	}
+}
+
+type apiKeyManager struct {
+	db *sql.DB
+}
+
+// apiKeyColumns lists the columns read by scanAPIKey.
+const apiKeyColumns = `id, name, prefix, key_hash, role, created_at, last_used_at, revoked_at`
+
+// Create inserts a new API key into the api_keys table.
+func (am *apiKeyManager) Create(ctx context.Context, key *model.APIKey) error {
+	query := `INSERT INTO api_keys (id, name, prefix, key_hash, role, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, key.ID, key.Name, key.Prefix, key.Hash, key.Role, key.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetByHash retrieves the API key whose secret has the provided hash. Revoked keys are not returned.
+// It returns ErrNotFound if there is no such key.
+func (am *apiKeyManager) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scanAPIKey(tx.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// List retrieves every API key, revoked ones included, oldest first.
+func (am *apiKeyManager) List(ctx context.Context) ([]*model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at`
+
+	var keys []*model.APIKey
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke marks the API key as revoked at the provided time. It returns ErrNotFound if there is no such key
+// or it is already revoked.
+func (am *apiKeyManager) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, id, at)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if revoked == 0 {
+		tx.Rollback()
+		return ErrNotFound
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetLastUsed records the time the API key was last used at.
+func (am *apiKeyManager) SetLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, id, at)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// scanAPIKey reads an API key from a row of apiKeyColumns.
+func scanAPIKey(row scanner) (*model.APIKey, error) {
+	var key model.APIKey
+	var lastUsedAt, revokedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &key.Role, &key.CreatedAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
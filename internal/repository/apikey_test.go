@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+func TestAPIKeyManager(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE api_keys CASCADE")
+		require.NoError(t, err)
+	}()
+
+	createdAt := time.Date(2024, 5, 18, 5, 30, 0, 0, time.UTC)
+	keys := []*model.APIKey{
+		{ID: uuid.New(), Name: "dashboard", Prefix: "ya_dash", Hash: strings.Repeat("a", 64), Role: model.RoleRead, CreatedAt: createdAt},
+		{ID: uuid.New(), Name: "operator", Prefix: "ya_oper", Hash: strings.Repeat("b", 64), Role: model.RoleAdmin, CreatedAt: createdAt.Add(time.Hour)},
+	}
+	for _, key := range keys {
+		require.NoError(t, apiKeyRep.Create(context.Background(), key))
+	}
+	require.Error(t, apiKeyRep.Create(context.Background(), &model.APIKey{ID: uuid.New(), Name: "duplicate", Hash: keys[0].Hash, Role: model.RoleRead, CreatedAt: createdAt}))
+
+	key, err := apiKeyRep.GetByHash(context.Background(), keys[1].Hash)
+	require.NoError(t, err)
+	require.Equal(t, keys[1].ID, key.ID)
+	require.Equal(t, model.RoleAdmin, key.Role)
+	require.Nil(t, key.LastUsedAt)
+
+	_, err = apiKeyRep.GetByHash(context.Background(), strings.Repeat("c", 64))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	usedAt := createdAt.Add(24 * time.Hour)
+	require.NoError(t, apiKeyRep.SetLastUsed(context.Background(), keys[0].ID, usedAt))
+	require.NoError(t, apiKeyRep.Revoke(context.Background(), keys[1].ID, usedAt))
+	require.ErrorIs(t, apiKeyRep.Revoke(context.Background(), keys[1].ID, usedAt), ErrNotFound)
+	require.ErrorIs(t, apiKeyRep.Revoke(context.Background(), uuid.New(), usedAt), ErrNotFound)
+
+	_, err = apiKeyRep.GetByHash(context.Background(), keys[1].Hash)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	all, err := apiKeyRep.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Equal(t, keys[0].ID, all[0].ID)
+	require.True(t, usedAt.Equal(*all[0].LastUsedAt))
+	require.Nil(t, all[0].RevokedAt)
+	require.True(t, usedAt.Equal(*all[1].RevokedAt))
+}
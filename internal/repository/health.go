@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion is the version of the latest migration in the migrations directory,
+// which the queries of this package are written against.
+const SchemaVersion = 15
+
+// HealthChecker defines the interface for checking the state of the database.
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (version int, dirty bool, err error)
+}
+
+// NewHealthChecker returns a new instance of HealthChecker.
+func NewHealthChecker(db *sql.DB) HealthChecker {
+	return &healthChecker{
+		db: db,
+	}
+}
+
+type healthChecker struct {
+	db *sql.DB
+}
+
+// Ping verifies that the database is reachable.
+func (hc *healthChecker) Ping(ctx context.Context) error {
+	return hc.db.PingContext(ctx)
+}
+
+// SchemaVersion retrieves the version of the latest applied migration and whether it failed halfway.
+// It returns ErrNotFound if no migration has been applied.
+func (hc *healthChecker) SchemaVersion(ctx context.Context) (int, bool, error) {
+	query := `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	var version int
+	var dirty bool
+	err := hc.db.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, ErrNotFound
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return version, dirty, nil
+}
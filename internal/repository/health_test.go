@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecker(t *testing.T) {
+	checker := NewHealthChecker(db)
+
+	require.NoError(t, checker.Ping(context.Background()))
+
+	version, dirty, err := checker.SchemaVersion(context.Background())
+	require.NoError(t, err)
+	require.False(t, dirty)
+	require.Equal(t, SchemaVersion, version)
+}
+
+func TestSchemaVersion(t *testing.T) {
+	entries, err := os.ReadDir("../../migrations")
+	require.NoError(t, err)
+
+	latest := 0
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		require.NoError(t, err, entry.Name())
+		latest = max(latest, version)
+	}
+	require.Equal(t, latest, SchemaVersion)
+}
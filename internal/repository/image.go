@@ -2,16 +2,41 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/google/uuid"
+
+	"github.com/EgMeln/YoungAstrologer/internal/imaging"
 	"github.com/EgMeln/YoungAstrologer/internal/model"
 )
 
+// ErrNotFound is returned when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicate is returned when an image of the source is already stored for the date.
+var ErrDuplicate = errors.New("image already exists")
+
 // ImageManager defines the interface for managing images.
 type ImageManager interface {
-	Create(image *model.Image) error
-	GetByDate(date string) (*model.Image, error)
-	GetAll() ([]*model.Image, error)
+	Create(ctx context.Context, image *model.Image) error
+	GetByDate(ctx context.Context, source, date string) (*model.Image, error)
+	GetHDDataByDate(ctx context.Context, source, date string) ([]byte, error)
+	GetAll(ctx context.Context) ([]*model.Image, error)
+	GetDates(ctx context.Context, source, from, to string) ([]string, error)
+	GetRandomDate(ctx context.Context, source string) (string, error)
+	List(ctx context.Context, opts model.ListOptions) ([]*model.ImageMetadata, error)
+	Search(ctx context.Context, opts model.SearchOptions) ([]*model.SearchResult, error)
+	HasBlob(ctx context.Context, checksum string) (bool, error)
+	GetDatabaseBlobs(ctx context.Context, limit int) ([]*model.Blob, error)
+	SetBlobKey(ctx context.Context, checksum, blobKey string) error
+	GetRendition(ctx context.Context, source, date, name string) (*model.Rendition, error)
+	GetWithoutRenditions(ctx context.Context, after uuid.UUID, limit int) ([]*model.Image, error)
+	CreateRenditions(ctx context.Context, imageID uuid.UUID, renditions []*model.Rendition) error
+	GetStats(ctx context.Context) (*model.StorageStats, error)
 }
 
 // NewImageManager returns a new instance of ImageManager.
@@ -25,20 +50,101 @@ type imageManager struct {
 	db *sql.DB
 }
 
-// Create inserts a new image into the images table.
-func (im *imageManager) Create(image *model.Image) error {
-	query := `INSERT INTO images (id, date, explanation, media_type, title, data) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (date) DO NOTHING`
+// dateColumn selects the date of an image in the YYYY-MM-DD format used by the models.
+const dateColumn = `to_char(date, 'YYYY-MM-DD')`
+
+// infoColumns lists the columns read by infoScanner. The last column lists the other images
+// with the same checksum as comma-separated "source date" pairs.
+const infoColumns = `format, width, height, checksum, dominant_color, camera_make, camera_model, exposure_time, f_number, iso, focal_length,
+	to_char(captured_at, 'YYYY-MM-DD"T"HH24:MI:SS'),
+	(SELECT string_agg(o.source || ' ' || to_char(o.date, 'YYYY-MM-DD'), ',' ORDER BY o.date, o.source)
+		FROM images o WHERE o.checksum = images.checksum AND o.id <> images.id)`
+
+// Data columns of an image; the data of images with a checksum is stored in the blobs table.
+const (
+	dataColumn      = `COALESCE((SELECT blobs.data FROM blobs WHERE blobs.checksum = images.checksum), data)`
+	hdDataColumn    = `COALESCE((SELECT blobs.data FROM blobs WHERE blobs.checksum = images.hd_checksum), hd_data)`
+	blobKeyColumn   = `COALESCE((SELECT blobs.blob_key FROM blobs WHERE blobs.checksum = images.checksum), blob_key)`
+	hdBlobKeyColumn = `COALESCE((SELECT blobs.blob_key FROM blobs WHERE blobs.checksum = images.hd_checksum), hd_blob_key)`
+)
+
+// imageColumns lists the columns read by scanImage.
+const imageColumns = `id, source, ` + dateColumn + `, explanation, media_type, title, url, hd_url, copyright, service_version, video_provider, thumbnail_url,
+	` + dataColumn + `, COALESCE(size, 0), COALESCE(hd_size, 0), ` + blobKeyColumn + `, ` + hdBlobKeyColumn + `, ` + infoColumns
+
+// metadataColumns lists the columns read by scanMetadata; the data columns are only measured.
+const metadataColumns = `id, source, ` + dateColumn + `, title, media_type, explanation, url, hd_url, copyright, service_version, video_provider, thumbnail_url,
+	COALESCE(size, octet_length(data), 0), COALESCE(hd_size, octet_length(hd_data), 0), ` + infoColumns
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Create inserts a new image into the images table along with its renditions.
+// If an image of the source is already stored for the date, nothing is inserted and ErrDuplicate is returned.
+// The data of the image is stored in the blobs table once per checksum; the sizes and
+// checksums of the data are set on the image if they are not set.
+func (im *imageManager) Create(ctx context.Context, image *model.Image) error {
+	query := `INSERT INTO images (id, source, date, explanation, media_type, title, url, hd_url, copyright, service_version, video_provider, thumbnail_url,
+		size, hd_size, hd_checksum,
+		format, width, height, checksum, dominant_color, camera_make, camera_model, exposure_time, f_number, iso, focal_length, captured_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+		$16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27) ON CONFLICT (source, date) DO NOTHING`
+
+	if len(image.Data) > 0 {
+		image.Size = int64(len(image.Data))
+		if image.Checksum == "" {
+			image.Checksum = imaging.Checksum(image.Data)
+		}
+	}
+	if len(image.HDData) > 0 {
+		image.HDSize = int64(len(image.HDData))
+		if image.HDChecksum == "" {
+			image.HDChecksum = imaging.Checksum(image.HDData)
+		}
+	}
+
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
 
-	tx, err := im.db.Begin()
+	videoProvider, thumbnailURL := videoColumns(image.Video)
+	args := []interface{}{image.ID, image.Source, image.Date, image.Explanation, image.MediaType, image.Title, image.URL,
+		nullString(image.HDURL), nullString(image.Copyright), nullString(image.ServiceVersion),
+		videoProvider, thumbnailURL, nullInt64(image.Size), nullInt64(image.HDSize), nullString(image.HDChecksum)}
+	result, err := tx.ExecContext(ctx, query, append(args, infoArgs(image.ImageInfo)...)...)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
-	_, err = tx.Exec(query, image.ID, image.Date, image.Explanation, image.MediaType, image.Title, image.Data)
+	inserted, err := result.RowsAffected()
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
+	if inserted == 0 {
+		tx.Rollback()
+		return ErrDuplicate
+	}
+
+	blobs := []*model.Blob{
+		{Checksum: image.Checksum, Data: image.Data, BlobKey: image.BlobKey, Size: image.Size},
+		{Checksum: image.HDChecksum, Data: image.HDData, BlobKey: image.HDBlobKey, Size: image.HDSize},
+	}
+	for _, blob := range blobs {
+		if err := insertBlob(ctx, tx, blob); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := insertRenditions(ctx, tx, image.ID, image.Renditions); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return err
@@ -47,21 +153,22 @@ func (im *imageManager) Create(image *model.Image) error {
 	return nil
 }
 
-// GetByDate retrieves an image from the images table by the specified date.
-func (im *imageManager) GetByDate(date string) (*model.Image, error) {
-	query := `SELECT id, date, explanation, media_type, title, data FROM images WHERE date = $1`
+// GetByDate retrieves an image of the source from the images table by the specified date.
+// The high definition rendition is not loaded; use GetHDDataByDate for it.
+// It returns ErrNotFound if the image is not stored.
+func (im *imageManager) GetByDate(ctx context.Context, source, date string) (*model.Image, error) {
+	query := `SELECT ` + imageColumns + ` FROM images WHERE source = $1 AND date = $2`
 
-	var image model.Image
-	tx, err := im.db.Begin()
+	tx, err := im.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	err = tx.QueryRow(query, date).Scan(&image.ID, &image.Date, &image.Explanation, &image.MediaType, &image.Title, &image.Data)
+	image, err := scanImage(tx.QueryRowContext(ctx, query, source, date))
 	if err != nil {
 		tx.Rollback()
 		if err == sql.ErrNoRows {
-			return nil, nil
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
@@ -69,20 +176,46 @@ func (im *imageManager) GetByDate(date string) (*model.Image, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &image, nil
+	return image, nil
+}
+
+// GetHDDataByDate retrieves the high definition rendition of an image of the source by the specified date.
+// It returns ErrNotFound if the image is not stored and nil if its high definition rendition is not stored.
+func (im *imageManager) GetHDDataByDate(ctx context.Context, source, date string) ([]byte, error) {
+	query := `SELECT ` + hdDataColumn + ` FROM images WHERE source = $1 AND date = $2`
+
+	var data []byte
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, query, source, date).Scan(&data)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 // GetAll retrieves all images from the images table.
-func (im *imageManager) GetAll() ([]*model.Image, error) {
-	query := `SELECT id, date, explanation, media_type, title, data FROM images`
+func (im *imageManager) GetAll(ctx context.Context) ([]*model.Image, error) {
+	query := `SELECT ` + imageColumns + ` FROM images`
 
 	var images []*model.Image
-	tx, err := im.db.Begin()
+	tx, err := im.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := tx.Query(query)
+	rows, err := tx.QueryContext(ctx, query)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -90,13 +223,386 @@ func (im *imageManager) GetAll() ([]*model.Image, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var image model.Image
+		image, err := scanImage(rows)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// GetDates retrieves the dates of all stored images of the source within the inclusive range [from, to].
+func (im *imageManager) GetDates(ctx context.Context, source, from, to string) ([]string, error) {
+	query := `SELECT ` + dateColumn + ` FROM images WHERE source = $1 AND date BETWEEN $2 AND $3 ORDER BY date`
 
-		err := rows.Scan(&image.ID, &image.Date, &image.Explanation, &image.MediaType, &image.Title, &image.Data)
+	var dates []string
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, source, from, to)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date string
+
+		if err := rows.Scan(&date); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return dates, nil
+}
+
+// GetRandomDate retrieves the date of a randomly chosen stored image of the source.
+// It returns ErrNotFound if no image of the source is stored.
+func (im *imageManager) GetRandomDate(ctx context.Context, source string) (string, error) {
+	query := `SELECT ` + dateColumn + ` FROM images WHERE source = $1 ORDER BY random() LIMIT 1`
+
+	var date string
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.QueryRowContext(ctx, query, source).Scan(&date)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+	return date, nil
+}
+
+// List retrieves image metadata from the images table according to the given options.
+// The data columns are never read; only their sizes are reported.
+func (im *imageManager) List(ctx context.Context, opts model.ListOptions) ([]*model.ImageMetadata, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	order := "ASC"
+	if opts.Order == model.SortDesc {
+		order = "DESC"
+	}
+
+	if opts.Source != "" {
+		addCondition("source = $%d", opts.Source)
+	}
+	if opts.After != "" {
+		if opts.Order == model.SortDesc {
+			addCondition("date < $%d", opts.After)
+		} else {
+			addCondition("date > $%d", opts.After)
+		}
+	}
+	if opts.From != "" {
+		addCondition("date >= $%d", opts.From)
+	}
+	if opts.To != "" {
+		addCondition("date <= $%d", opts.To)
+	}
+	switch opts.Orientation {
+	case model.OrientationLandscape:
+		conditions = append(conditions, "width > height")
+	case model.OrientationPortrait:
+		conditions = append(conditions, "width < height")
+	case model.OrientationSquare:
+		conditions = append(conditions, "width = height")
+	}
+	if opts.MinWidth > 0 {
+		addCondition("width >= $%d", opts.MinWidth)
+	}
+	if opts.MinHeight > 0 {
+		addCondition("height >= $%d", opts.MinHeight)
+	}
+
+	query := `SELECT ` + metadataColumns + ` FROM images`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY date ` + order + `, source ` + order
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	var images []*model.ImageMetadata
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		image, err := scanMetadata(rows)
 		if err != nil {
 			tx.Rollback()
 			return nil, err
 		}
+		images = append(images, image)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// searchConfig is the text search configuration the search_vector column is built with.
+const searchConfig = "english"
+
+// headlineOptions configures the excerpts of the explanation returned by Search.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" ... \""
+
+// escapedText returns an SQL expression that HTML-escapes the text of the column before it is highlighted,
+// so that the <mark> tags added by ts_headline are the only markup in the excerpts returned by Search.
+func escapedText(column string) string {
+	return `replace(replace(replace(replace(replace(COALESCE(` + column + `, ''),
+		'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+// Search retrieves metadata of the images whose title or explanation matches the query, best ranked first.
+// Matches in the title rank higher than matches in the explanation; ties are broken by the newest date.
+func (im *imageManager) Search(ctx context.Context, opts model.SearchOptions) ([]*model.SearchResult, error) {
+	query := `SELECT ` + metadataColumns + `, ts_rank_cd(search_vector, q),
+		ts_headline('` + searchConfig + `', ` + escapedText("title") + `, q, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>'),
+		ts_headline('` + searchConfig + `', ` + escapedText("explanation") + `, q, '` + headlineOptions + `')
+		FROM images, websearch_to_tsquery('` + searchConfig + `', $1) q
+		WHERE search_vector @@ q AND ($2 = '' OR source = $2)
+		ORDER BY ts_rank_cd(search_vector, q) DESC, date DESC, source
+		LIMIT $3 OFFSET $4`
+
+	var results []*model.SearchResult
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, opts.Query, opts.Source, opts.Limit, opts.Offset)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result, err := scanSearchResult(rows)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// HasBlob reports whether data with the checksum is stored in the blobs table.
+func (im *imageManager) HasBlob(ctx context.Context, checksum string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM blobs WHERE checksum = $1)`
+
+	var exists bool
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRowContext(ctx, query, checksum).Scan(&exists)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetDatabaseBlobs retrieves up to limit blobs whose data is still stored in the blobs table.
+func (im *imageManager) GetDatabaseBlobs(ctx context.Context, limit int) ([]*model.Blob, error) {
+	query := `SELECT checksum, data, size FROM blobs WHERE data IS NOT NULL ORDER BY checksum LIMIT $1`
+
+	var blobs []*model.Blob
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blob model.Blob
+
+		if err := rows.Scan(&blob.Checksum, &blob.Data, &blob.Size); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		blobs = append(blobs, &blob)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs, nil
+}
+
+// SetBlobKey records the blob store key of the data with the checksum and removes the data from the blobs table.
+func (im *imageManager) SetBlobKey(ctx context.Context, checksum, blobKey string) error {
+	query := `UPDATE blobs SET blob_key = $2, data = NULL WHERE checksum = $1`
+
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, checksum, blobKey)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetRendition retrieves the named rendition of an image of the source by the specified date.
+// It returns ErrNotFound if the image or the rendition is not stored.
+func (im *imageManager) GetRendition(ctx context.Context, source, date, name string) (*model.Rendition, error) {
+	query := `SELECT r.name, r.content_type, r.width, r.height, r.size, r.data, r.blob_key
+		FROM image_renditions r JOIN images i ON i.id = r.image_id
+		WHERE i.source = $1 AND i.date = $2 AND r.name = $3`
+
+	var rendition model.Rendition
+	var blobKey sql.NullString
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, query, source, date, name).Scan(&rendition.Name, &rendition.ContentType,
+		&rendition.Width, &rendition.Height, &rendition.Size, &rendition.Data, &blobKey)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	rendition.BlobKey = blobKey.String
+
+	return &rendition, nil
+}
+
+// GetWithoutRenditions retrieves up to limit images with stored data but no renditions, ordered by ID
+// and starting after the given ID. Only the ID, source, date, data and blob key fields of the returned images are set.
+func (im *imageManager) GetWithoutRenditions(ctx context.Context, after uuid.UUID, limit int) ([]*model.Image, error) {
+	query := `SELECT id, source, ` + dateColumn + `, ` + dataColumn + `, ` + blobKeyColumn + ` FROM images
+		WHERE id > $1 AND (checksum IS NOT NULL OR octet_length(data) > 0 OR blob_key IS NOT NULL)
+		AND NOT EXISTS (SELECT 1 FROM image_renditions r WHERE r.image_id = images.id)
+		ORDER BY id LIMIT $2`
+
+	var images []*model.Image
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var image model.Image
+		var blobKey sql.NullString
+
+		if err := rows.Scan(&image.ID, &image.Source, &image.Date, &image.Data, &blobKey); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		image.BlobKey = blobKey.String
 		images = append(images, &image)
 	}
 	if err := rows.Err(); err != nil {
@@ -111,3 +617,231 @@ func (im *imageManager) GetAll() ([]*model.Image, error) {
 
 	return images, nil
 }
+
+// CreateRenditions stores the renditions of an image, replacing stored renditions with the same names.
+func (im *imageManager) CreateRenditions(ctx context.Context, imageID uuid.UUID, renditions []*model.Rendition) error {
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := insertRenditions(ctx, tx, imageID, renditions); err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// insertRenditions stores the renditions of an image within the transaction.
+func insertRenditions(ctx context.Context, tx *sql.Tx, imageID uuid.UUID, renditions []*model.Rendition) error {
+	query := `INSERT INTO image_renditions (image_id, name, content_type, width, height, size, data, blob_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (image_id, name) DO UPDATE SET content_type = EXCLUDED.content_type, width = EXCLUDED.width,
+		height = EXCLUDED.height, size = EXCLUDED.size, data = EXCLUDED.data, blob_key = EXCLUDED.blob_key`
+
+	for _, rendition := range renditions {
+		_, err := tx.ExecContext(ctx, query, imageID, rendition.Name, rendition.ContentType, rendition.Width, rendition.Height,
+			rendition.Size, rendition.Data, nullString(rendition.BlobKey))
+		if err != nil {
+			return fmt.Errorf("insert %s rendition: %w", rendition.Name, err)
+		}
+	}
+	return nil
+}
+
+// GetStats counts the stored images and the bytes of their data. Data in the blobs table is counted once
+// however many images share it; the renditions are not counted.
+func (im *imageManager) GetStats(ctx context.Context) (*model.StorageStats, error) {
+	query := `SELECT count(*),
+		COALESCE(sum(size) FILTER (WHERE checksum IS NULL), 0) + COALESCE(sum(hd_size) FILTER (WHERE hd_checksum IS NULL), 0)
+		+ (SELECT COALESCE(sum(size), 0) FROM blobs)
+		FROM images`
+
+	var stats model.StorageStats
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, query).Scan(&stats.Images, &stats.Bytes)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// insertBlob stores the data of a blob in the blobs table unless data with the same checksum is already stored.
+// Blobs without a checksum are not stored. The references of the images to the blobs table
+// are checked when the transaction commits, so the blob may be inserted after the image.
+func insertBlob(ctx context.Context, tx *sql.Tx, blob *model.Blob) error {
+	if blob.Checksum == "" {
+		return nil
+	}
+
+	query := `INSERT INTO blobs (checksum, data, blob_key, size) VALUES ($1, $2, $3, $4) ON CONFLICT (checksum) DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, blob.Checksum, blob.Data, nullString(blob.BlobKey), blob.Size)
+	return err
+}
+
+// scanImage scans a row selected with imageColumns into an image.
+func scanImage(row scanner) (*model.Image, error) {
+	var image model.Image
+	var url, hdURL, copyright, serviceVersion, videoProvider, thumbnailURL, blobKey, hdBlobKey sql.NullString
+	var info infoScanner
+
+	dest := []interface{}{&image.ID, &image.Source, &image.Date, &image.Explanation, &image.MediaType, &image.Title,
+		&url, &hdURL, &copyright, &serviceVersion, &videoProvider, &thumbnailURL, &image.Data,
+		&image.Size, &image.HDSize, &blobKey, &hdBlobKey}
+	err := row.Scan(append(dest, info.dest()...)...)
+	if err != nil {
+		return nil, err
+	}
+	image.ImageInfo = info.info()
+	image.BlobKey = blobKey.String
+	image.HDBlobKey = hdBlobKey.String
+	image.URL = url.String
+	image.HDURL = hdURL.String
+	image.Copyright = copyright.String
+	image.ServiceVersion = serviceVersion.String
+	image.Video = newVideo(image.MediaType, videoProvider, thumbnailURL)
+
+	return &image, nil
+}
+
+// scanMetadata scans a row selected with metadataColumns into image metadata.
+// Columns selected after metadataColumns are scanned into extra.
+func scanMetadata(row scanner, extra ...interface{}) (*model.ImageMetadata, error) {
+	var image model.ImageMetadata
+	var url, hdURL, copyright, serviceVersion, videoProvider, thumbnailURL sql.NullString
+	var info infoScanner
+
+	dest := []interface{}{&image.ID, &image.Source, &image.Date, &image.Title, &image.MediaType, &image.Explanation,
+		&url, &hdURL, &copyright, &serviceVersion, &videoProvider, &thumbnailURL, &image.Size, &image.HDSize}
+	dest = append(dest, info.dest()...)
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	image.ImageInfo = info.info()
+	image.URL = url.String
+	image.HDURL = hdURL.String
+	image.Copyright = copyright.String
+	image.ServiceVersion = serviceVersion.String
+	image.Video = newVideo(image.MediaType, videoProvider, thumbnailURL)
+
+	return &image, nil
+}
+
+// scanSearchResult scans a row selected by Search into a search result.
+func scanSearchResult(row scanner) (*model.SearchResult, error) {
+	var result model.SearchResult
+
+	image, err := scanMetadata(row, &result.Rank, &result.Highlight.Title, &result.Highlight.Explanation)
+	if err != nil {
+		return nil, err
+	}
+	result.ImageMetadata = image
+
+	return &result, nil
+}
+
+// infoScanner holds the nullable columns listed in infoColumns while they are scanned.
+type infoScanner struct {
+	format, checksum, dominantColor, cameraMake, cameraModel, exposureTime, capturedAt, sameAs sql.NullString
+	width, height, iso                                                                         sql.NullInt64
+	fNumber, focalLength                                                                       sql.NullFloat64
+}
+
+// dest returns the scan destinations of the columns listed in infoColumns.
+func (s *infoScanner) dest() []interface{} {
+	return []interface{}{&s.format, &s.width, &s.height, &s.checksum, &s.dominantColor, &s.cameraMake, &s.cameraModel,
+		&s.exposureTime, &s.fNumber, &s.iso, &s.focalLength, &s.capturedAt, &s.sameAs}
+}
+
+// info returns the scanned image info. EXIF is nil if none of its fields is stored.
+func (s *infoScanner) info() model.ImageInfo {
+	info := model.ImageInfo{
+		Format:        s.format.String,
+		Width:         int(s.width.Int64),
+		Height:        int(s.height.Int64),
+		Checksum:      s.checksum.String,
+		DominantColor: s.dominantColor.String,
+	}
+
+	exif := model.EXIF{
+		CameraMake:   s.cameraMake.String,
+		CameraModel:  s.cameraModel.String,
+		ExposureTime: s.exposureTime.String,
+		FNumber:      s.fNumber.Float64,
+		ISO:          int(s.iso.Int64),
+		FocalLength:  s.focalLength.Float64,
+		CapturedAt:   s.capturedAt.String,
+	}
+	if exif != (model.EXIF{}) {
+		info.EXIF = &exif
+	}
+
+	if s.sameAs.String != "" {
+		for _, ref := range strings.Split(s.sameAs.String, ",") {
+			source, date, _ := strings.Cut(ref, " ")
+			info.SameAs = append(info.SameAs, model.ImageRef{Source: source, Date: date})
+		}
+	}
+	return info
+}
+
+// infoArgs converts the stored image info into nullable column values in the order of infoColumns.
+func infoArgs(info model.ImageInfo) []interface{} {
+	exif := model.EXIF{}
+	if info.EXIF != nil {
+		exif = *info.EXIF
+	}
+	return []interface{}{nullString(info.Format), nullInt64(int64(info.Width)), nullInt64(int64(info.Height)),
+		nullString(info.Checksum), nullString(info.DominantColor), nullString(exif.CameraMake), nullString(exif.CameraModel),
+		nullString(exif.ExposureTime), nullFloat64(exif.FNumber), nullInt64(int64(exif.ISO)), nullFloat64(exif.FocalLength),
+		nullString(exif.CapturedAt)}
+}
+
+// nullFloat64 converts an optional number into a nullable column value.
+func nullFloat64(f float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: f, Valid: f != 0}
+}
+
+// nullInt64 converts an optional number into a nullable column value.
+func nullInt64(n int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: n, Valid: n != 0}
+}
+
+// nullString converts an optional string into a nullable column value.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// videoColumns converts the video details of an image into nullable column values.
+func videoColumns(video *model.Video) (sql.NullString, sql.NullString) {
+	if video == nil {
+		return sql.NullString{}, sql.NullString{}
+	}
+	return sql.NullString{String: video.Provider, Valid: true}, nullString(video.ThumbnailURL)
+}
+
+// newVideo builds the video details of an image from nullable column values.
+func newVideo(mediaType string, provider, thumbnailURL sql.NullString) *model.Video {
+	if mediaType != model.MediaTypeVideo {
+		return nil
+	}
+	return &model.Video{
+		Provider:     provider.String,
+		ThumbnailURL: thumbnailURL.String,
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
@@ -11,12 +12,13 @@ import (
 
 func TestImageManager_Create(t *testing.T) {
 	defer func() {
-		_, err := db.Exec("TRUNCATE TABLE images CASCADE")
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
 		require.NoError(t, err)
 	}()
 
 	image := &model.Image{
 		ID:          uuid.New(),
+		Source:      model.DefaultSource,
 		Date:        "2024-05-18",
 		Title:       "A Beautiful Nebula",
 		Explanation: "This is an explanation of the beautiful nebula.",
@@ -24,29 +26,46 @@ func TestImageManager_Create(t *testing.T) {
 		Data:        []byte{0x89, 0x50, 0x4E, 0x47},
 	}
 
-	err := imageRep.Create(image)
+	err := imageRep.Create(context.Background(), image)
 	require.NoError(t, err)
 
 }
 func TestImageManager_GetByDate(t *testing.T) {
 	defer func() {
-		_, err := db.Exec("TRUNCATE TABLE images CASCADE")
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
 		require.NoError(t, err)
 	}()
 
 	image := &model.Image{
 		ID:          uuid.New(),
+		Source:      model.DefaultSource,
 		Date:        "2024-05-18",
 		Title:       "A Beautiful Nebula",
 		Explanation: "This is an explanation of the beautiful nebula.",
 		MediaType:   "image",
 		Data:        []byte{0x89, 0x50, 0x4E, 0x47},
+		ImageInfo: model.ImageInfo{
+			Format:        "png",
+			Width:         2048,
+			Height:        1024,
+			Checksum:      "0f343b0931126a20f133d67c2b018a3b5d2bb2ee5a1fd8b4c1c0a6c2f9d7e5f1",
+			DominantColor: "#102030",
+			EXIF: &model.EXIF{
+				CameraMake:   "Canon",
+				CameraModel:  "EOS R5",
+				ExposureTime: "1/250",
+				FNumber:      2.8,
+				ISO:          1600,
+				FocalLength:  135,
+				CapturedAt:   "2024-05-17T23:41:05",
+			},
+		},
 	}
 
-	err := imageRep.Create(image)
+	err := imageRep.Create(context.Background(), image)
 	require.NoError(t, err)
 
-	retrievedImage, err := imageRep.GetByDate(image.Date)
+	retrievedImage, err := imageRep.GetByDate(context.Background(), model.DefaultSource, image.Date)
 	require.NoError(t, err)
 
 	require.Equal(t, image, retrievedImage)
@@ -54,12 +73,13 @@ func TestImageManager_GetByDate(t *testing.T) {
 
 func TestImageManager_GetAll(t *testing.T) {
 	defer func() {
-		_, err := db.Exec("TRUNCATE TABLE images CASCADE")
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
 		require.NoError(t, err)
 	}()
 
 	image1 := &model.Image{
 		ID:          uuid.New(),
+		Source:      model.DefaultSource,
 		Date:        "2024-05-18",
 		Explanation: "This is an explanation of the beautiful nebula.",
 		MediaType:   "image",
@@ -69,23 +89,435 @@ func TestImageManager_GetAll(t *testing.T) {
 
 	image2 := &model.Image{
 		ID:          uuid.New(),
+		Source:      model.DefaultSource,
 		Date:        "2024-05-19",
 		Explanation: "This is another explanation of the beautiful nebula.",
 		MediaType:   "image",
 		Title:       "Another Beautiful Nebula",
-		Data:        []byte{0x89, 0x50, 0x4E, 0x47},
+		Data:        []byte{0x47, 0x49, 0x46, 0x38},
 	}
 
-	err := imageRep.Create(image1)
+	err := imageRep.Create(context.Background(), image1)
 	require.NoError(t, err)
 
-	err = imageRep.Create(image2)
+	err = imageRep.Create(context.Background(), image2)
 	require.NoError(t, err)
 
-	images, err := imageRep.GetAll()
+	images, err := imageRep.GetAll(context.Background())
 	require.NoError(t, err)
 
 	require.Len(t, images, 2)
 
 	require.Equal(t, []*model.Image{image1, image2}, images)
 }
+
+func TestImageManager_GetDates(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	for _, date := range []string{"2024-05-17", "2024-05-18", "2024-05-20", "2024-05-22"} {
+		err := imageRep.Create(context.Background(), &model.Image{
+			ID:          uuid.New(),
+			Source:      model.DefaultSource,
+			Date:        date,
+			Title:       "A Beautiful Nebula",
+			Explanation: "This is an explanation of the beautiful nebula.",
+			MediaType:   "image",
+			Data:        []byte{0x89, 0x50, 0x4E, 0x47},
+		})
+		require.NoError(t, err)
+	}
+
+	dates, err := imageRep.GetDates(context.Background(), model.DefaultSource, "2024-05-18", "2024-05-21")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"2024-05-18", "2024-05-20"}, dates)
+}
+
+func TestImageManager_List(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	for _, date := range []string{"2024-05-17", "2024-05-18", "2024-05-19", "2024-05-20"} {
+		err := imageRep.Create(context.Background(), &model.Image{
+			ID:          uuid.New(),
+			Source:      model.DefaultSource,
+			Date:        date,
+			Title:       "A Beautiful Nebula",
+			Explanation: "This is an explanation of the beautiful nebula.",
+			MediaType:   "image",
+			Data:        []byte{0x89, 0x50, 0x4E, 0x47},
+		})
+		require.NoError(t, err)
+	}
+
+	dates := func(images []*model.ImageMetadata) []string {
+		var result []string
+		for _, image := range images {
+			result = append(result, image.Date)
+		}
+		return result
+	}
+
+	images, err := imageRep.List(context.Background(), model.ListOptions{Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-05-17", "2024-05-18"}, dates(images))
+	require.Equal(t, int64(4), images[0].Size)
+
+	images, err = imageRep.List(context.Background(), model.ListOptions{Limit: 2, After: "2024-05-18"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-05-19", "2024-05-20"}, dates(images))
+
+	images, err = imageRep.List(context.Background(), model.ListOptions{Order: model.SortDesc, After: "2024-05-20", From: "2024-05-18"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-05-19", "2024-05-18"}, dates(images))
+
+	images, err = imageRep.List(context.Background(), model.ListOptions{To: "2024-05-17"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-05-17"}, dates(images))
+}
+
+func TestImageManager_ListDimensions(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	for date, dimensions := range map[string][2]int{
+		"2024-05-17": {1920, 1080},
+		"2024-05-18": {1080, 1920},
+		"2024-05-19": {800, 800},
+		"2024-05-20": {0, 0},
+	} {
+		err := imageRep.Create(context.Background(), &model.Image{
+			ID:        uuid.New(),
+			Source:    model.DefaultSource,
+			Date:      date,
+			MediaType: "image",
+			ImageInfo: model.ImageInfo{Width: dimensions[0], Height: dimensions[1]},
+		})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name          string
+		opts          model.ListOptions
+		expectedDates []string
+	}{
+		{name: "Landscape", opts: model.ListOptions{Orientation: model.OrientationLandscape}, expectedDates: []string{"2024-05-17"}},
+		{name: "Portrait", opts: model.ListOptions{Orientation: model.OrientationPortrait}, expectedDates: []string{"2024-05-18"}},
+		{name: "Square", opts: model.ListOptions{Orientation: model.OrientationSquare}, expectedDates: []string{"2024-05-19"}},
+		{name: "MinWidth", opts: model.ListOptions{MinWidth: 1000}, expectedDates: []string{"2024-05-17", "2024-05-18"}},
+		{name: "MinWidthAndHeight", opts: model.ListOptions{MinWidth: 1000, MinHeight: 1500}, expectedDates: []string{"2024-05-18"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images, err := imageRep.List(context.Background(), tt.opts)
+			require.NoError(t, err)
+
+			var dates []string
+			for _, image := range images {
+				dates = append(dates, image.Date)
+			}
+			require.Equal(t, tt.expectedDates, dates)
+		})
+	}
+}
+
+func TestImageManager_Search(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	images := []*model.Image{
+		{Source: model.DefaultSource, Date: "2024-05-17", Title: "The Andromeda Galaxy", Explanation: "Our nearest large neighbour."},
+		{Source: model.DefaultSource, Date: "2024-05-18", Title: "M33", Explanation: "The Triangulum galaxy lies beyond Andromeda."},
+		{Source: model.DefaultSource, Date: "2024-05-19", Title: "A Beautiful Nebula", Explanation: "Glowing hydrogen gas."},
+		{Source: "drop", Date: "2024-05-18", Title: "Andromeda Rising <script>", Explanation: "Seen from the observatory."},
+	}
+	for _, image := range images {
+		image.ID = uuid.New()
+		image.MediaType = "image"
+		require.NoError(t, imageRep.Create(context.Background(), image))
+	}
+
+	results, err := imageRep.Search(context.Background(), model.SearchOptions{Source: model.DefaultSource, Query: "andromeda", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "2024-05-17", results[0].Date)
+	require.Equal(t, "2024-05-18", results[1].Date)
+	require.Greater(t, results[0].Rank, results[1].Rank)
+	require.Equal(t, "The <mark>Andromeda</mark> Galaxy", results[0].Highlight.Title)
+	require.Contains(t, results[1].Highlight.Explanation, "<mark>Andromeda</mark>")
+
+	results, err = imageRep.Search(context.Background(), model.SearchOptions{Source: model.DefaultSource, Query: "andromeda", Limit: 10, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "2024-05-18", results[0].Date)
+
+	results, err = imageRep.Search(context.Background(), model.SearchOptions{Source: "drop", Query: "andromeda", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Contains(t, results[0].Highlight.Title, "<mark>Andromeda</mark>")
+	require.Contains(t, results[0].Highlight.Title, "&lt;script&gt;")
+	require.NotContains(t, results[0].Highlight.Title, "<script>")
+
+	results, err = imageRep.Search(context.Background(), model.SearchOptions{Query: "andromeda -triangulum", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	results, err = imageRep.Search(context.Background(), model.SearchOptions{Query: "nebulae", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "2024-05-19", results[0].Date)
+}
+
+func TestImageManager_Renditions(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	image := &model.Image{
+		ID:        uuid.New(),
+		Source:    model.DefaultSource,
+		Date:      "2024-05-18",
+		MediaType: "image",
+		Data:      []byte{0x89, 0x50, 0x4E, 0x47},
+		Renditions: []*model.Rendition{
+			{Name: model.RenditionThumb, ContentType: "image/jpeg", Width: 320, Height: 160, Data: []byte{0xFF, 0xD8}, Size: 2},
+		},
+	}
+	require.NoError(t, imageRep.Create(context.Background(), image))
+
+	legacy := &model.Image{ID: uuid.New(), Source: model.DefaultSource, Date: "2024-05-19", MediaType: "image", Data: []byte{0x47, 0x49, 0x46}}
+	require.NoError(t, imageRep.Create(context.Background(), legacy))
+	video := &model.Image{ID: uuid.New(), Source: model.DefaultSource, Date: "2024-05-20", MediaType: "video", Video: &model.Video{Provider: "vimeo"}}
+	require.NoError(t, imageRep.Create(context.Background(), video))
+
+	rendition, err := imageRep.GetRendition(context.Background(), model.DefaultSource, "2024-05-18", model.RenditionThumb)
+	require.NoError(t, err)
+	require.Equal(t, image.Renditions[0], rendition)
+
+	_, err = imageRep.GetRendition(context.Background(), model.DefaultSource, "2024-05-18", model.RenditionMedium)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = imageRep.GetRendition(context.Background(), model.DefaultSource, "2024-05-19", model.RenditionThumb)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	images, err := imageRep.GetWithoutRenditions(context.Background(), uuid.Nil, 10)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	require.Equal(t, legacy.ID, images[0].ID)
+	require.Equal(t, legacy.Data, images[0].Data)
+
+	err = imageRep.CreateRenditions(context.Background(), legacy.ID, []*model.Rendition{
+		{Name: model.RenditionThumb, ContentType: "image/png", Width: 3, Height: 1, BlobKey: "images/thumb", Size: 10},
+	})
+	require.NoError(t, err)
+
+	rendition, err = imageRep.GetRendition(context.Background(), model.DefaultSource, "2024-05-19", model.RenditionThumb)
+	require.NoError(t, err)
+	require.Equal(t, "images/thumb", rendition.BlobKey)
+	require.Nil(t, rendition.Data)
+
+	images, err = imageRep.GetWithoutRenditions(context.Background(), uuid.Nil, 10)
+	require.NoError(t, err)
+	require.Empty(t, images)
+}
+
+func TestImageManager_CreateVideo(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	video := &model.Image{
+		ID:          uuid.New(),
+		Source:      model.DefaultSource,
+		Date:        "2024-05-18",
+		Title:       "A Beautiful Eclipse",
+		Explanation: "This is an explanation of the beautiful eclipse.",
+		MediaType:   "video",
+		URL:         "https://www.youtube.com/embed/abcdef",
+		Video: &model.Video{
+			Provider:     "youtube",
+			ThumbnailURL: "https://img.youtube.com/vi/abcdef/0.jpg",
+		},
+		Data: []byte{0xFF, 0xD8, 0xFF},
+	}
+	noThumbnail := &model.Image{
+		ID:        uuid.New(),
+		Source:    model.DefaultSource,
+		Date:      "2024-05-19",
+		Title:     "Another Beautiful Eclipse",
+		MediaType: "video",
+		URL:       "https://player.vimeo.com/video/123",
+		Video:     &model.Video{Provider: "vimeo"},
+	}
+
+	require.NoError(t, imageRep.Create(context.Background(), video))
+	require.NoError(t, imageRep.Create(context.Background(), noThumbnail))
+
+	retrievedVideo, err := imageRep.GetByDate(context.Background(), model.DefaultSource, video.Date)
+	require.NoError(t, err)
+	require.Equal(t, video, retrievedVideo)
+
+	images, err := imageRep.List(context.Background(), model.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+	require.Equal(t, noThumbnail.Video, images[1].Video)
+	require.Zero(t, images[1].Size)
+}
+
+func TestImageManager_GetHDDataByDate(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	image := &model.Image{
+		ID:             uuid.New(),
+		Source:         model.DefaultSource,
+		Date:           "2024-05-18",
+		Title:          "A Beautiful Nebula",
+		Explanation:    "This is an explanation of the beautiful nebula.",
+		MediaType:      "image",
+		URL:            "https://apod.nasa.gov/apod/image/2405/nebula.jpg",
+		HDURL:          "https://apod.nasa.gov/apod/image/2405/nebula_hd.jpg",
+		Copyright:      "Jane Doe",
+		ServiceVersion: "v1",
+		Data:           []byte{0x89, 0x50, 0x4E, 0x47},
+		HDData:         []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A},
+	}
+
+	err := imageRep.Create(context.Background(), image)
+	require.NoError(t, err)
+
+	hdData, err := imageRep.GetHDDataByDate(context.Background(), model.DefaultSource, image.Date)
+	require.NoError(t, err)
+	require.Equal(t, image.HDData, hdData)
+
+	retrievedImage, err := imageRep.GetByDate(context.Background(), model.DefaultSource, image.Date)
+	require.NoError(t, err)
+	require.Nil(t, retrievedImage.HDData)
+	require.Equal(t, image.HDURL, retrievedImage.HDURL)
+	require.Equal(t, image.Copyright, retrievedImage.Copyright)
+	require.Equal(t, image.ServiceVersion, retrievedImage.ServiceVersion)
+
+	images, err := imageRep.List(context.Background(), model.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	require.Equal(t, int64(6), images[0].HDSize)
+
+	_, err = imageRep.GetHDDataByDate(context.Background(), model.DefaultSource, "2024-05-19")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestImageManager_Blobs(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	data := []byte{0x89, 0x50, 0x4E, 0x47}
+	hdData := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A}
+	first := &model.Image{ID: uuid.New(), Source: model.DefaultSource, Date: "2024-05-18", MediaType: "image", Data: data, HDData: hdData}
+	repeat := &model.Image{ID: uuid.New(), Source: "drop", Date: "2024-05-19", MediaType: "image", Data: data}
+	require.NoError(t, imageRep.Create(context.Background(), first))
+	require.NoError(t, imageRep.Create(context.Background(), repeat))
+	require.Equal(t, first.Checksum, repeat.Checksum)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM blobs").Scan(&count))
+	require.Equal(t, 2, count)
+
+	exists, err := imageRep.HasBlob(context.Background(), first.Checksum)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	retrieved, err := imageRep.GetByDate(context.Background(), "drop", repeat.Date)
+	require.NoError(t, err)
+	require.Equal(t, data, retrieved.Data)
+	require.Equal(t, []model.ImageRef{{Source: model.DefaultSource, Date: first.Date}}, retrieved.SameAs)
+
+	blobs, err := imageRep.GetDatabaseBlobs(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, blobs, 2)
+
+	for _, blob := range blobs {
+		require.NoError(t, imageRep.SetBlobKey(context.Background(), blob.Checksum, "blobs/"+blob.Checksum))
+	}
+
+	blobs, err = imageRep.GetDatabaseBlobs(context.Background(), 10)
+	require.NoError(t, err)
+	require.Empty(t, blobs)
+
+	migrated, err := imageRep.GetByDate(context.Background(), model.DefaultSource, first.Date)
+	require.NoError(t, err)
+	require.Nil(t, migrated.Data)
+	require.Equal(t, "blobs/"+first.Checksum, migrated.BlobKey)
+	require.Equal(t, "blobs/"+first.HDChecksum, migrated.HDBlobKey)
+	require.Equal(t, int64(4), migrated.Size)
+	require.Equal(t, int64(6), migrated.HDSize)
+	require.Equal(t, []model.ImageRef{{Source: "drop", Date: repeat.Date}}, migrated.SameAs)
+}
+
+func TestImageManager_SourceUniqueness(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	apod := &model.Image{ID: uuid.New(), Source: model.DefaultSource, Date: "2024-05-18", Title: "APOD", MediaType: "image"}
+	drop := &model.Image{ID: uuid.New(), Source: "drop", Date: "2024-05-18", Title: "Drop", MediaType: "image"}
+	duplicate := &model.Image{ID: uuid.New(), Source: "drop", Date: "2024-05-18", Title: "Duplicate", MediaType: "image"}
+	require.NoError(t, imageRep.Create(context.Background(), apod))
+	require.NoError(t, imageRep.Create(context.Background(), drop))
+	require.ErrorIs(t, imageRep.Create(context.Background(), duplicate), ErrDuplicate)
+
+	retrieved, err := imageRep.GetByDate(context.Background(), "drop", "2024-05-18")
+	require.NoError(t, err)
+	require.Equal(t, drop.ID, retrieved.ID)
+
+	dates, err := imageRep.GetDates(context.Background(), "drop", "2024-05-01", "2024-05-31")
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-05-18"}, dates)
+
+	images, err := imageRep.List(context.Background(), model.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+
+	images, err = imageRep.List(context.Background(), model.ListOptions{Source: model.DefaultSource})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	require.Equal(t, apod.ID, images[0].ID)
+	require.Equal(t, model.DefaultSource, images[0].Source)
+}
+
+func TestImageManager_GetRandomDate(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE images, blobs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	_, err := imageRep.GetRandomDate(context.Background(), model.DefaultSource)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	for _, date := range []string{"2024-05-18", "2024-05-19"} {
+		image := &model.Image{ID: uuid.New(), Source: model.DefaultSource, Date: date, Title: "A Beautiful Nebula", MediaType: "image"}
+		require.NoError(t, imageRep.Create(context.Background(), image))
+	}
+
+	date, err := imageRep.GetRandomDate(context.Background(), model.DefaultSource)
+	require.NoError(t, err)
+	require.Contains(t, []string{"2024-05-18", "2024-05-19"}, date)
+
+	_, err = imageRep.GetRandomDate(context.Background(), "drop")
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/EgMeln/YoungAstrologer/internal/metrics"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/tracing"
+)
+
+// NewInstrumentedImageManager returns an ImageManager that observes the duration of every call to next
+// in the database query duration metric, labelled by method name, and traces it in a span.
+func NewInstrumentedImageManager(next ImageManager) ImageManager {
+	return &instrumentedImageManager{
+		next: next,
+	}
+}
+
+type instrumentedImageManager struct {
+	next ImageManager
+}
+
+// start starts the span of a call to the method and returns the function that ends it with the error
+// of the call and records its duration. ErrNotFound and ErrDuplicate are expected outcomes and do not fail
+// the span.
+func start(ctx context.Context, method string) (context.Context, func(err *error)) {
+	begin := time.Now()
+	ctx, span := tracing.Start(ctx, "ImageManager."+method, attribute.String("db.system", "postgresql"))
+	return ctx, func(err *error) {
+		metrics.DBQueryDuration.WithLabelValues(method).Observe(time.Since(begin).Seconds())
+		if errors.Is(*err, ErrNotFound) || errors.Is(*err, ErrDuplicate) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, *err)
+	}
+}
+
+func (im *instrumentedImageManager) Create(ctx context.Context, image *model.Image) (err error) {
+	ctx, end := start(ctx, "Create")
+	defer end(&err)
+	return im.next.Create(ctx, image)
+}
+
+func (im *instrumentedImageManager) GetByDate(ctx context.Context, source, date string) (_ *model.Image, err error) {
+	ctx, end := start(ctx, "GetByDate")
+	defer end(&err)
+	return im.next.GetByDate(ctx, source, date)
+}
+
+func (im *instrumentedImageManager) GetHDDataByDate(ctx context.Context, source, date string) (_ []byte, err error) {
+	ctx, end := start(ctx, "GetHDDataByDate")
+	defer end(&err)
+	return im.next.GetHDDataByDate(ctx, source, date)
+}
+
+func (im *instrumentedImageManager) GetAll(ctx context.Context) (_ []*model.Image, err error) {
+	ctx, end := start(ctx, "GetAll")
+	defer end(&err)
+	return im.next.GetAll(ctx)
+}
+
+func (im *instrumentedImageManager) GetDates(ctx context.Context, source, from, to string) (_ []string, err error) {
+	ctx, end := start(ctx, "GetDates")
+	defer end(&err)
+	return im.next.GetDates(ctx, source, from, to)
+}
+
+func (im *instrumentedImageManager) GetRandomDate(ctx context.Context, source string) (_ string, err error) {
+	ctx, end := start(ctx, "GetRandomDate")
+	defer end(&err)
+	return im.next.GetRandomDate(ctx, source)
+}
+
+func (im *instrumentedImageManager) List(ctx context.Context, opts model.ListOptions) (_ []*model.ImageMetadata, err error) {
+	ctx, end := start(ctx, "List")
+	defer end(&err)
+	return im.next.List(ctx, opts)
+}
+
+func (im *instrumentedImageManager) Search(ctx context.Context, opts model.SearchOptions) (_ []*model.SearchResult, err error) {
+	ctx, end := start(ctx, "Search")
+	defer end(&err)
+	return im.next.Search(ctx, opts)
+}
+
+func (im *instrumentedImageManager) HasBlob(ctx context.Context, checksum string) (_ bool, err error) {
+	ctx, end := start(ctx, "HasBlob")
+	defer end(&err)
+	return im.next.HasBlob(ctx, checksum)
+}
+
+func (im *instrumentedImageManager) GetDatabaseBlobs(ctx context.Context, limit int) (_ []*model.Blob, err error) {
+	ctx, end := start(ctx, "GetDatabaseBlobs")
+	defer end(&err)
+	return im.next.GetDatabaseBlobs(ctx, limit)
+}
+
+func (im *instrumentedImageManager) SetBlobKey(ctx context.Context, checksum, blobKey string) (err error) {
+	ctx, end := start(ctx, "SetBlobKey")
+	defer end(&err)
+	return im.next.SetBlobKey(ctx, checksum, blobKey)
+}
+
+func (im *instrumentedImageManager) GetRendition(ctx context.Context, source, date, name string) (_ *model.Rendition, err error) {
+	ctx, end := start(ctx, "GetRendition")
+	defer end(&err)
+	return im.next.GetRendition(ctx, source, date, name)
+}
+
+func (im *instrumentedImageManager) GetWithoutRenditions(ctx context.Context, after uuid.UUID, limit int) (_ []*model.Image, err error) {
+	ctx, end := start(ctx, "GetWithoutRenditions")
+	defer end(&err)
+	return im.next.GetWithoutRenditions(ctx, after, limit)
+}
+
+func (im *instrumentedImageManager) CreateRenditions(ctx context.Context, imageID uuid.UUID, renditions []*model.Rendition) (err error) {
+	ctx, end := start(ctx, "CreateRenditions")
+	defer end(&err)
+	return im.next.CreateRenditions(ctx, imageID, renditions)
+}
+
+func (im *instrumentedImageManager) GetStats(ctx context.Context) (_ *model.StorageStats, err error) {
+	ctx, end := start(ctx, "GetStats")
+	defer end(&err)
+	return im.next.GetStats(ctx)
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+// JobRunManager defines the interface for managing job runs.
+type JobRunManager interface {
+	Create(ctx context.Context, run *model.JobRun) error
+	List(ctx context.Context, job string, limit int) ([]*model.JobRun, error)
+	HasSucceeded(ctx context.Context, job, source, apodDate string) (bool, error)
+	Latest(ctx context.Context, job, source, status string) (*model.JobRun, error)
+}
+
+// NewJobRunManager returns a new instance of JobRunManager.
+func NewJobRunManager(db *sql.DB) JobRunManager {
+	return &jobRunManager{
+		db: db,
+	}
+}
+
+type jobRunManager struct {
+	db *sql.DB
+}
+
+// Create inserts a new job run into the job_runs table.
+func (jm *jobRunManager) Create(ctx context.Context, run *model.JobRun) error {
+	query := `INSERT INTO job_runs (id, job, source, apod_date, started_at, finished_at, status, attempts, saved, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	tx, err := jm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, run.ID, run.Job, run.Source, nullString(run.APODDate), run.StartedAt, run.FinishedAt, run.Status, run.Attempts, run.Saved, nullString(run.Error))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// jobRunColumns lists the columns read by scanJobRun.
+const jobRunColumns = `id, job, source, apod_date, started_at, finished_at, status, attempts, saved, error`
+
+// List retrieves the most recent runs of the specified job, newest first.
+// An empty job name lists the runs of all jobs.
+func (jm *jobRunManager) List(ctx context.Context, job string, limit int) ([]*model.JobRun, error) {
+	query := `SELECT ` + jobRunColumns + ` FROM job_runs WHERE $1 = '' OR job = $1 ORDER BY started_at DESC LIMIT $2`
+
+	var runs []*model.JobRun
+	tx, err := jm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, job, limit)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// HasSucceeded reports whether a successful run of the specified job was recorded for the source and APOD date.
+func (jm *jobRunManager) HasSucceeded(ctx context.Context, job, source, apodDate string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM job_runs WHERE job = $1 AND source = $2 AND apod_date = $3 AND status = $4)`
+
+	var exists bool
+	tx, err := jm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRowContext(ctx, query, job, source, apodDate, model.JobStatusSuccess).Scan(&exists)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Latest retrieves the most recently started run of the job of the source with the status.
+// Empty job and status values match every job and status. It returns ErrNotFound if there is no such run.
+func (jm *jobRunManager) Latest(ctx context.Context, job, source, status string) (*model.JobRun, error) {
+	query := `SELECT ` + jobRunColumns + ` FROM job_runs
+		WHERE ($1 = '' OR job = $1) AND source = $2 AND ($3 = '' OR status = $3) ORDER BY started_at DESC LIMIT 1`
+
+	tx, err := jm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := scanJobRun(tx.QueryRowContext(ctx, query, job, source, status))
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// scanJobRun scans a row selected with jobRunColumns into a job run.
+func scanJobRun(row scanner) (*model.JobRun, error) {
+	var run model.JobRun
+	var apodDate, runErr sql.NullString
+
+	err := row.Scan(&run.ID, &run.Job, &run.Source, &apodDate, &run.StartedAt, &run.FinishedAt, &run.Status, &run.Attempts, &run.Saved, &runErr)
+	if err != nil {
+		return nil, err
+	}
+	run.APODDate = apodDate.String
+	run.Error = runErr.String
+
+	return &run, nil
+}
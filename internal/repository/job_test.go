@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+)
+
+func TestJobRunManager_List(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE job_runs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	startedAt := time.Date(2024, 5, 18, 5, 30, 0, 0, time.UTC)
+	runs := []*model.JobRun{
+		{
+			ID:         uuid.New(),
+			Job:        "daily",
+			StartedAt:  startedAt,
+			FinishedAt: startedAt.Add(time.Minute),
+			Status:     model.JobStatusFailed,
+			Attempts:   5,
+			Error:      "unexpected status code: 503",
+		},
+		{
+			ID:         uuid.New(),
+			Job:        "daily",
+			StartedAt:  startedAt.Add(24 * time.Hour),
+			FinishedAt: startedAt.Add(24*time.Hour + time.Minute),
+			Status:     model.JobStatusSuccess,
+			Attempts:   1,
+			Saved:      2,
+		},
+		{
+			ID:         uuid.New(),
+			Job:        "backfill",
+			StartedAt:  startedAt.Add(time.Hour),
+			FinishedAt: startedAt.Add(2 * time.Hour),
+			Status:     model.JobStatusSuccess,
+			Attempts:   1,
+			Saved:      30,
+		},
+	}
+	for _, run := range runs {
+		require.NoError(t, jobRunRep.Create(context.Background(), run))
+	}
+
+	daily, err := jobRunRep.List(context.Background(), "daily", 10)
+	require.NoError(t, err)
+	require.Len(t, daily, 2)
+	require.Equal(t, runs[1].ID, daily[0].ID)
+	require.Equal(t, runs[0].Error, daily[1].Error)
+	require.True(t, runs[0].StartedAt.Equal(daily[1].StartedAt))
+
+	all, err := jobRunRep.List(context.Background(), "", 2)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Equal(t, runs[1].ID, all[0].ID)
+	require.Equal(t, runs[2].ID, all[1].ID)
+}
+
+func TestJobRunManager_HasSucceeded(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE job_runs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	startedAt := time.Date(2024, 5, 18, 5, 30, 0, 0, time.UTC)
+	for _, run := range []*model.JobRun{
+		{ID: uuid.New(), Job: "daily", Source: model.DefaultSource, APODDate: "2024-05-17", Status: model.JobStatusSuccess},
+		{ID: uuid.New(), Job: "daily", Source: model.DefaultSource, APODDate: "2024-05-18", Status: model.JobStatusFailed},
+		{ID: uuid.New(), Job: "daily", Source: "drop", APODDate: "2024-05-18", Status: model.JobStatusSuccess},
+	} {
+		run.StartedAt, run.FinishedAt = startedAt, startedAt
+		require.NoError(t, jobRunRep.Create(context.Background(), run))
+	}
+
+	succeeded, err := jobRunRep.HasSucceeded(context.Background(), "daily", model.DefaultSource, "2024-05-17")
+	require.NoError(t, err)
+	require.True(t, succeeded)
+
+	succeeded, err = jobRunRep.HasSucceeded(context.Background(), "daily", model.DefaultSource, "2024-05-18")
+	require.NoError(t, err)
+	require.False(t, succeeded)
+
+	succeeded, err = jobRunRep.HasSucceeded(context.Background(), "backfill", model.DefaultSource, "2024-05-17")
+	require.NoError(t, err)
+	require.False(t, succeeded)
+
+	succeeded, err = jobRunRep.HasSucceeded(context.Background(), "daily", "drop", "2024-05-18")
+	require.NoError(t, err)
+	require.True(t, succeeded)
+}
+
+func TestJobRunManager_Latest(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE job_runs CASCADE")
+		require.NoError(t, err)
+	}()
+
+	startedAt := time.Date(2024, 5, 18, 5, 30, 0, 0, time.UTC)
+	runs := []*model.JobRun{
+		{ID: uuid.New(), Job: "daily", Source: model.DefaultSource, APODDate: "2024-05-17", Status: model.JobStatusSuccess},
+		{ID: uuid.New(), Job: "backfill", Source: model.DefaultSource, Status: model.JobStatusFailed, Error: "unexpected status code: 503"},
+		{ID: uuid.New(), Job: "daily", Source: model.DefaultSource, APODDate: "2024-05-18", Status: model.JobStatusFailed, Error: "not published"},
+	}
+	for i, run := range runs {
+		run.StartedAt = startedAt.Add(time.Duration(i) * time.Hour)
+		run.FinishedAt = run.StartedAt
+		require.NoError(t, jobRunRep.Create(context.Background(), run))
+	}
+
+	latest, err := jobRunRep.Latest(context.Background(), "daily", model.DefaultSource, model.JobStatusSuccess)
+	require.NoError(t, err)
+	require.Equal(t, runs[0].ID, latest.ID)
+	require.Equal(t, "2024-05-17", latest.APODDate)
+
+	latest, err = jobRunRep.Latest(context.Background(), "", model.DefaultSource, model.JobStatusFailed)
+	require.NoError(t, err)
+	require.Equal(t, runs[2].ID, latest.ID)
+	require.Equal(t, "not published", latest.Error)
+
+	_, err = jobRunRep.Latest(context.Background(), "daily", "drop", "")
+	require.ErrorIs(t, err, ErrNotFound)
+}
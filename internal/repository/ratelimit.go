@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/EgMeln/YoungAstrologer/internal/ratelimit"
+)
+
+// rateLimitSweepInterval is how often the buckets that have been refilled completely are deleted.
+const rateLimitSweepInterval = time.Minute
+
+// NewRateLimiter returns a ratelimit.Limiter that keeps the buckets in the rate_limits table, so that
+// the replicas of the service share them. The buckets are refilled according to the clock of the replica
+// taking a token, so the clocks of the replicas should be synchronized.
+func NewRateLimiter(db *sql.DB) ratelimit.Limiter {
+	return &rateLimiter{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+type rateLimiter struct {
+	db  *sql.DB
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// Allow takes a token from the bucket of the key. The bucket is locked until the token is taken, so
+// concurrent requests of a client are counted exactly. Buckets that have been refilled completely are
+// deleted once a minute.
+func (rl *rateLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	now := rl.now().UTC()
+	bucket := ratelimit.NewBucket(limit, now)
+
+	tx, err := rl.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	if rl.sweepDue(now) {
+		_, err = tx.ExecContext(ctx, `DELETE FROM rate_limits WHERE full_at <= $1`, now)
+		if err != nil {
+			tx.Rollback()
+			return ratelimit.Result{}, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO rate_limits (key, tokens, updated_at, full_at) VALUES ($1, $2, $3, $3)
+		ON CONFLICT (key) DO NOTHING`, key, bucket.Tokens, bucket.Updated)
+	if err != nil {
+		tx.Rollback()
+		return ratelimit.Result{}, err
+	}
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE`, key).
+		Scan(&bucket.Tokens, &bucket.Updated)
+	if err != nil {
+		tx.Rollback()
+		return ratelimit.Result{}, err
+	}
+
+	result := bucket.Take(now, limit)
+	_, err = tx.ExecContext(ctx, `UPDATE rate_limits SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1`,
+		key, bucket.Tokens, bucket.Updated, bucket.FullAt(limit))
+	if err != nil {
+		tx.Rollback()
+		return ratelimit.Result{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return result, nil
+}
+
+// sweepDue reports whether the full buckets are to be deleted, which only one caller per interval is told.
+func (rl *rateLimiter) sweepDue(now time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) < rateLimitSweepInterval {
+		return false
+	}
+	rl.lastSweep = now
+	return true
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/ratelimit"
+)
+
+func TestRateLimiter(t *testing.T) {
+	defer func() {
+		_, err := db.Exec("TRUNCATE TABLE rate_limits")
+		require.NoError(t, err)
+	}()
+
+	limit := ratelimit.PerPeriod(1, time.Hour, 5)
+
+	// Concurrent requests of a client take the tokens one at a time.
+	var wg sync.WaitGroup
+	allowed := make(chan bool, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := limiter.Allow(context.Background(), "GET /images client", limit)
+			require.NoError(t, err)
+			allowed <- result.Allowed
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	require.Equal(t, 5, count)
+
+	result, err := limiter.Allow(context.Background(), "GET /images client", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 5, result.Limit)
+	require.Equal(t, 0, result.Remaining)
+	require.Greater(t, result.RetryAfter, 59*time.Minute)
+
+	result, err = limiter.Allow(context.Background(), "GET /images other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 4, result.Remaining)
+}
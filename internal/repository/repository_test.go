@@ -11,12 +11,17 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/ory/dockertest"
+
+	"github.com/EgMeln/YoungAstrologer/internal/ratelimit"
 )
 
 var (
 	db *sql.DB
 
-	imageRep ImageManager
+	imageRep  ImageManager
+	jobRunRep JobRunManager
+	apiKeyRep APIKeyManager
+	limiter   ratelimit.Limiter
 )
 
 func TestMain(m *testing.M) {
@@ -73,6 +78,9 @@ func TestMain(m *testing.M) {
 	defer db.Close()
 
 	imageRep = NewImageManager(db)
+	jobRunRep = NewJobRunManager(db)
+	apiKeyRep = NewAPIKeyManager(db)
+	limiter = NewRateLimiter(db)
 
 	code := m.Run()
 
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+)
+
+const (
+	// apiKeyPrefix starts every API key secret, so leaked keys are easy to recognize.
+	apiKeyPrefix = "ya_"
+	// apiKeyBytes is the number of random bytes of an API key secret.
+	apiKeyBytes = 32
+	// displayedPrefixLength is the length of the beginning of a secret stored to identify the key.
+	displayedPrefixLength = len(apiKeyPrefix) + 8
+	// maxAPIKeyNameLength is the maximum length of the name of an API key.
+	maxAPIKeyNameLength = 100
+	// lastUsedResolution is how outdated the recorded last use of a key may get, which saves
+	// a write for every request.
+	lastUsedResolution = time.Minute
+)
+
+// APIKeyService defines the interface for the API key service.
+type APIKeyService interface {
+	Create(ctx context.Context, name, role string) (*model.APIKey, string, error)
+	List(ctx context.Context) ([]*model.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	Authenticate(ctx context.Context, secret string) (*model.APIKey, error)
+}
+
+// NewAPIKeyService returns a new instance of APIKeyService.
+func NewAPIKeyService(apiKeyManager repository.APIKeyManager) APIKeyService {
+	return &apiKeyService{
+		apiKeyManager: apiKeyManager,
+		now:           time.Now,
+	}
+}
+
+type apiKeyService struct {
+	apiKeyManager repository.APIKeyManager
+	now           func() time.Time
+}
+
+// Create generates a new API key with the name and role and stores its hash in the database.
+// It returns the key along with its secret, which is not stored and cannot be retrieved later.
+func (as *apiKeyService) Create(ctx context.Context, name, role string) (*model.APIKey, string, error) {
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, "", InvalidInput("name must be 1 to %d characters", maxAPIKeyNameLength)
+	}
+	if role != model.RoleRead && role != model.RoleAdmin {
+		return nil, "", InvalidInput("role must be %s or %s, got %q", model.RoleRead, model.RoleAdmin, role)
+	}
+
+	random := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", err
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	key := &model.APIKey{
+		ID:        uuid.New(),
+		Name:      name,
+		Prefix:    secret[:displayedPrefixLength],
+		Hash:      hashAPIKey(secret),
+		Role:      role,
+		CreatedAt: as.now().UTC(),
+	}
+	if err := as.apiKeyManager.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// List retrieves every API key, revoked ones included.
+func (as *apiKeyService) List(ctx context.Context) ([]*model.APIKey, error) {
+	return as.apiKeyManager.List(ctx)
+}
+
+// Revoke revokes the API key, which is rejected from then on. It returns a NotFound error if there is
+// no such key or it is already revoked.
+func (as *apiKeyService) Revoke(ctx context.Context, id uuid.UUID) error {
+	err := as.apiKeyManager.Revoke(ctx, id, as.now().UTC())
+	if errors.Is(err, repository.ErrNotFound) {
+		return NotFound("no active API key %s", id)
+	}
+	return err
+}
+
+// Authenticate retrieves the API key with the secret and records that it was used. It returns
+// an Unauthenticated error if there is no such key or it was revoked.
+func (as *apiKeyService) Authenticate(ctx context.Context, secret string) (*model.APIKey, error) {
+	key, err := as.apiKeyManager.GetByHash(ctx, hashAPIKey(secret))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, Unauthenticated("API key is invalid or revoked")
+	}
+	if err != nil {
+		return nil, Unavailable(err, "API keys are unavailable")
+	}
+
+	now := as.now().UTC()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := as.apiKeyManager.SetLastUsed(ctx, key.ID, now); err != nil {
+			logging.FromContext(ctx).Warnf("Error recording use of API key %s: %v", key.ID, err)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
+
+// hashAPIKey returns the hex-encoded SHA-256 hash of the secret. Secrets are random, so a fast hash
+// does not make them easier to guess.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+)
+
+type mockAPIKeyManager struct {
+	CreateFunc      func(key *model.APIKey) error
+	GetByHashFunc   func(hash string) (*model.APIKey, error)
+	ListFunc        func() ([]*model.APIKey, error)
+	RevokeFunc      func(id uuid.UUID, at time.Time) error
+	SetLastUsedFunc func(id uuid.UUID, at time.Time) error
+}
+
+func (m *mockAPIKeyManager) Create(ctx context.Context, key *model.APIKey) error {
+	return m.CreateFunc(key)
+}
+
+func (m *mockAPIKeyManager) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	return m.GetByHashFunc(hash)
+}
+
+func (m *mockAPIKeyManager) List(ctx context.Context) ([]*model.APIKey, error) {
+	return m.ListFunc()
+}
+
+func (m *mockAPIKeyManager) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.RevokeFunc(id, at)
+}
+
+func (m *mockAPIKeyManager) SetLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.SetLastUsedFunc(id, at)
+}
+
+func TestAPIKeyService_Create(t *testing.T) {
+	t.Parallel()
+
+	var stored *model.APIKey
+	apiKeySvc := NewAPIKeyService(&mockAPIKeyManager{
+		CreateFunc: func(key *model.APIKey) error {
+			stored = key
+			return nil
+		},
+	})
+
+	key, secret, err := apiKeySvc.Create(context.Background(), "operator", model.RoleAdmin)
+	require.NoError(t, err)
+	require.Same(t, stored, key)
+	require.True(t, strings.HasPrefix(secret, apiKeyPrefix))
+	require.Len(t, secret, len(apiKeyPrefix)+43)
+	require.Equal(t, secret[:displayedPrefixLength], key.Prefix)
+	require.Equal(t, hashAPIKey(secret), key.Hash)
+	require.Equal(t, model.RoleAdmin, key.Role)
+
+	_, other, err := apiKeySvc.Create(context.Background(), "dashboard", model.RoleRead)
+	require.NoError(t, err)
+	require.NotEqual(t, secret, other)
+
+	_, _, err = apiKeySvc.Create(context.Background(), "", model.RoleRead)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	_, _, err = apiKeySvc.Create(context.Background(), "operator", "owner")
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	t.Parallel()
+
+	active := uuid.New()
+	apiKeySvc := NewAPIKeyService(&mockAPIKeyManager{
+		RevokeFunc: func(id uuid.UUID, at time.Time) error {
+			if id != active {
+				return repository.ErrNotFound
+			}
+			return nil
+		},
+	})
+
+	require.NoError(t, apiKeySvc.Revoke(context.Background(), active))
+	require.ErrorIs(t, apiKeySvc.Revoke(context.Background(), uuid.New()), ErrNotFound)
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 18, 12, 0, 0, 0, time.UTC)
+	recently := now.Add(-lastUsedResolution / 2)
+	longAgo := now.Add(-time.Hour)
+
+	tests := []struct {
+		name             string
+		key              *model.APIKey
+		getErr           error
+		expectedErr      error
+		expectedLastUsed *time.Time
+		expectedTouched  bool
+	}{
+		{
+			name:             "FirstUse",
+			key:              &model.APIKey{ID: uuid.New(), Role: model.RoleRead},
+			expectedLastUsed: &now,
+			expectedTouched:  true,
+		},
+		{
+			name:             "UsedLongAgo",
+			key:              &model.APIKey{ID: uuid.New(), Role: model.RoleRead, LastUsedAt: &longAgo},
+			expectedLastUsed: &now,
+			expectedTouched:  true,
+		},
+		{
+			name:             "UsedRecently",
+			key:              &model.APIKey{ID: uuid.New(), Role: model.RoleRead, LastUsedAt: &recently},
+			expectedLastUsed: &recently,
+		},
+		{
+			name:        "Unknown",
+			getErr:      repository.ErrNotFound,
+			expectedErr: ErrUnauthenticated,
+		},
+		{
+			name:        "DatabaseError",
+			getErr:      errors.New("connection refused"),
+			expectedErr: ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			touched := false
+			apiKeySvc := &apiKeyService{
+				apiKeyManager: &mockAPIKeyManager{
+					GetByHashFunc: func(hash string) (*model.APIKey, error) {
+						require.Equal(t, hashAPIKey("ya_secret"), hash)
+						return tt.key, tt.getErr
+					},
+					SetLastUsedFunc: func(id uuid.UUID, at time.Time) error {
+						require.Equal(t, tt.key.ID, id)
+						require.Equal(t, now, at)
+						touched = true
+						return nil
+					},
+				},
+				now: func() time.Time { return now },
+			}
+
+			key, err := apiKeySvc.Authenticate(context.Background(), "ya_secret")
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedLastUsed, key.LastUsedAt)
+			require.Equal(t, tt.expectedTouched, touched)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Use errors.Is to check the kind of an error returned by a service.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnavailable  = errors.New("upstream unavailable")
+	ErrConflict     = errors.New("conflict")
+	// ErrUnauthenticated and ErrForbidden report requests without valid credentials and requests whose
+	// credentials do not grant the operation.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+	// ErrRateLimited reports requests of a client that exceeded its rate limit.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// Error is a domain error of one of the kinds above.
+// Its message describes the problem to clients and never includes the underlying cause.
+type Error struct {
+	kind    error
+	message string
+	cause   error
+}
+
+// Error returns the message followed by the cause, if any.
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.message
+	}
+	return e.message + ": " + e.cause.Error()
+}
+
+// Message returns the description of the error that is safe to show to clients.
+func (e *Error) Message() string {
+	return e.message
+}
+
+// Unwrap returns the kind and the cause of the error.
+func (e *Error) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.cause}
+}
+
+func newError(kind, cause error, format string, args ...any) *Error {
+	return &Error{
+		kind:    kind,
+		message: fmt.Sprintf(format, args...),
+		cause:   cause,
+	}
+}
+
+// Describe returns a description of err that leaves out the cause of domain errors, which may hold
+// internal details: the kind and the message of a domain error, or the text of any other error.
+func Describe(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.kind.Error() + ": " + domainErr.message
+	}
+	return err.Error()
+}
+
+// NotFound returns an error reporting that the requested resource does not exist.
+func NotFound(format string, args ...any) error {
+	return newError(ErrNotFound, nil, format, args...)
+}
+
+// InvalidInput returns an error reporting that a request is malformed or out of range.
+func InvalidInput(format string, args ...any) error {
+	return newError(ErrInvalidInput, nil, format, args...)
+}
+
+// Conflict returns an error reporting that a request conflicts with the current state.
+func Conflict(format string, args ...any) error {
+	return newError(ErrConflict, nil, format, args...)
+}
+
+// Unauthenticated returns an error reporting that a request lacks valid credentials.
+func Unauthenticated(format string, args ...any) error {
+	return newError(ErrUnauthenticated, nil, format, args...)
+}
+
+// Forbidden returns an error reporting that the credentials of a request do not grant the operation.
+func Forbidden(format string, args ...any) error {
+	return newError(ErrForbidden, nil, format, args...)
+}
+
+// RateLimited returns an error reporting that the client sent more requests than its rate limit allows.
+func RateLimited(format string, args ...any) error {
+	return newError(ErrRateLimited, nil, format, args...)
+}
+
+// Unavailable returns an error reporting that a service the request depends on failed with cause.
+func Unavailable(cause error, format string, args ...any) error {
+	return newError(ErrUnavailable, cause, format, args...)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("connection refused")
+
+	tests := []struct {
+		name            string
+		err             error
+		expectedKind    error
+		expectedMessage string
+		expectedError   string
+	}{
+		{
+			name:            "NotFound",
+			err:             NotFound("no %s image for date %s", "apod", "2024-05-18"),
+			expectedKind:    ErrNotFound,
+			expectedMessage: "no apod image for date 2024-05-18",
+			expectedError:   "no apod image for date 2024-05-18",
+		},
+		{
+			name:            "InvalidInput",
+			err:             InvalidInput("limit must be positive"),
+			expectedKind:    ErrInvalidInput,
+			expectedMessage: "limit must be positive",
+			expectedError:   "limit must be positive",
+		},
+		{
+			name:            "Conflict",
+			err:             Conflict("backfill is already running"),
+			expectedKind:    ErrConflict,
+			expectedMessage: "backfill is already running",
+			expectedError:   "backfill is already running",
+		},
+		{
+			name:            "Unauthenticated",
+			err:             Unauthenticated("API key is invalid or revoked"),
+			expectedKind:    ErrUnauthenticated,
+			expectedMessage: "API key is invalid or revoked",
+			expectedError:   "API key is invalid or revoked",
+		},
+		{
+			name:            "Forbidden",
+			err:             Forbidden("the admin role is required"),
+			expectedKind:    ErrForbidden,
+			expectedMessage: "the admin role is required",
+			expectedError:   "the admin role is required",
+		},
+		{
+			name:            "RateLimited",
+			err:             RateLimited("rate limit exceeded, retry in 2 seconds"),
+			expectedKind:    ErrRateLimited,
+			expectedMessage: "rate limit exceeded, retry in 2 seconds",
+			expectedError:   "rate limit exceeded, retry in 2 seconds",
+		},
+		{
+			name:            "Unavailable",
+			err:             Unavailable(cause, "blob store is unavailable"),
+			expectedKind:    ErrUnavailable,
+			expectedMessage: "blob store is unavailable",
+			expectedError:   "blob store is unavailable: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, tt.err, tt.expectedKind)
+			require.Equal(t, tt.expectedError, tt.err.Error())
+
+			var domainErr *Error
+			require.ErrorAs(t, tt.err, &domainErr)
+			require.Equal(t, tt.expectedMessage, domainErr.Message())
+		})
+	}
+
+	require.ErrorIs(t, Unavailable(cause, "blob store is unavailable"), cause)
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New(`Get "https://api.nasa.gov/planetary/apod?api_key=SECRET_KEY": connection refused`)
+	require.Equal(t, "upstream unavailable: NASA API is unavailable", Describe(Unavailable(cause, "NASA API is unavailable")))
+	require.Equal(t, "not found: no apod image for date 2024-05-18", Describe(fmt.Errorf("get image: %w", NotFound("no apod image for date 2024-05-18"))))
+	require.Equal(t, "unexpected status code: 503", Describe(errors.New("unexpected status code: 503")))
+}
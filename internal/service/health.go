@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+)
+
+// HealthService defines the interface for checking the dependencies of the service.
+type HealthService interface {
+	CheckDatabase(ctx context.Context) error
+}
+
+// NewHealthService returns a new instance of HealthService.
+func NewHealthService(healthChecker repository.HealthChecker) HealthService {
+	return &healthService{
+		healthChecker: healthChecker,
+	}
+}
+
+type healthService struct {
+	healthChecker repository.HealthChecker
+}
+
+// CheckDatabase verifies that the database is reachable and migrated to the schema version the service expects.
+// It returns an Unavailable error describing the problem otherwise.
+func (hs *healthService) CheckDatabase(ctx context.Context) error {
+	if err := hs.healthChecker.Ping(ctx); err != nil {
+		return Unavailable(err, "database is unreachable")
+	}
+
+	version, dirty, err := hs.healthChecker.SchemaVersion(ctx)
+	if errors.Is(err, repository.ErrNotFound) {
+		return Unavailable(err, "database is not migrated")
+	}
+	if err != nil {
+		return Unavailable(err, "database schema version is unknown")
+	}
+	if dirty {
+		return Unavailable(fmt.Errorf("migration %d is dirty", version), "database migration %d failed", version)
+	}
+	if version != repository.SchemaVersion {
+		return Unavailable(fmt.Errorf("schema version %d", version), "database schema is at version %d, expected %d", version, repository.SchemaVersion)
+	}
+	return nil
+}
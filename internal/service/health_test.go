@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+)
+
+type mockHealthChecker struct {
+	PingFunc          func() error
+	SchemaVersionFunc func() (int, bool, error)
+}
+
+func (m *mockHealthChecker) Ping(ctx context.Context) error {
+	return m.PingFunc()
+}
+
+func (m *mockHealthChecker) SchemaVersion(ctx context.Context) (int, bool, error) {
+	return m.SchemaVersionFunc()
+}
+
+func TestHealthService_CheckDatabase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		pingErr       error
+		version       int
+		dirty         bool
+		versionErr    error
+		expectedError string
+	}{
+		{name: "Ready", version: repository.SchemaVersion},
+		{name: "Unreachable", pingErr: errors.New("connection refused"), expectedError: "database is unreachable"},
+		{name: "NotMigrated", versionErr: repository.ErrNotFound, expectedError: "database is not migrated"},
+		{name: "Dirty", version: repository.SchemaVersion, dirty: true, expectedError: fmt.Sprintf("database migration %d failed", repository.SchemaVersion)},
+		{name: "Outdated", version: repository.SchemaVersion - 1, expectedError: fmt.Sprintf("database schema is at version %d, expected %d", repository.SchemaVersion-1, repository.SchemaVersion)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			healthSvc := NewHealthService(&mockHealthChecker{
+				PingFunc: func() error {
+					return tt.pingErr
+				},
+				SchemaVersionFunc: func() (int, bool, error) {
+					return tt.version, tt.dirty, tt.versionErr
+				},
+			})
+
+			err := healthSvc.CheckDatabase(context.Background())
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrUnavailable)
+			var domainErr *Error
+			require.ErrorAs(t, err, &domainErr)
+			require.Equal(t, tt.expectedError, domainErr.Message())
+		})
+	}
+}
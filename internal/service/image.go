@@ -2,42 +2,405 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/google/uuid"
 
+	"github.com/EgMeln/YoungAstrologer/internal/imaging"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
 	"github.com/EgMeln/YoungAstrologer/internal/model"
 	"github.com/EgMeln/YoungAstrologer/internal/repository"
+	"github.com/EgMeln/YoungAstrologer/internal/storage"
 )
 
 // ImageService defines the interface for the image service.
 type ImageService interface {
-	Save(image *model.Image) error
-	GetByDate(date string) (*model.Image, error)
-	GetAll() ([]*model.Image, error)
+	Save(ctx context.Context, image *model.Image) error
+	GetByDate(ctx context.Context, source, date string) (*model.Image, error)
+	GetHDDataByDate(ctx context.Context, source, date string) ([]byte, error)
+	GetAll(ctx context.Context) ([]*model.Image, error)
+	GetDates(ctx context.Context, source, from, to string) ([]string, error)
+	GetRandomDate(ctx context.Context, source string) (string, error)
+	List(ctx context.Context, opts model.ListOptions) ([]*model.ImageMetadata, error)
+	Search(ctx context.Context, opts model.SearchOptions) ([]*model.SearchResult, error)
+	GetRendition(ctx context.Context, source, date, name string) (*model.Rendition, error)
+	MigrateBlobs(ctx context.Context, batchSize int) (int, error)
+	GenerateRenditions(ctx context.Context, batchSize int) (int, error)
+	GetStats(ctx context.Context) (*model.StorageStats, error)
 }
 
+// errNoBlobStore is returned when image data is referenced by a blob key but no blob store is configured.
+var errNoBlobStore = errors.New("blob store is not configured")
+
 // NewImageService returns a new instance of ImageService.
-func NewImageService(imageManager repository.ImageManager) ImageService {
+// If blobStore is nil, image data is kept in the database.
+func NewImageService(imageManager repository.ImageManager, blobStore storage.BlobStore) ImageService {
 	return &imageService{
 		imageManager: imageManager,
+		blobStore:    blobStore,
 	}
 }
 
 type imageService struct {
 	imageManager repository.ImageManager
+	blobStore    storage.BlobStore
 }
 
-// Save generates a new UUID for the image and stores it in the database.
-func (is *imageService) Save(image *model.Image) error {
+// Save generates a new UUID for the image and stores it in the database along with its renditions.
+// Images without a source are attributed to the default source. The format, dimensions, checksum,
+// dominant color and EXIF fields of the image data are recorded and renditions are generated from it;
+// data that cannot be decoded is stored with its checksum only and without renditions.
+// The data is stored once per checksum, so images with the same picture share it. When a blob store is
+// configured, data that is not stored yet is uploaded to it and only its key is stored in the database;
+// the renditions uploaded are deleted again if the image is not stored. A conflict error is returned if an
+// image of the source is already stored for the date.
+func (is *imageService) Save(ctx context.Context, image *model.Image) error {
 	image.ID = uuid.New()
-	return is.imageManager.Create(image)
+	if image.Source == "" {
+		image.Source = model.DefaultSource
+	}
+	image.Size = int64(len(image.Data))
+	image.HDSize = int64(len(image.HDData))
+
+	if len(image.Data) > 0 {
+		decoded, format, err := imaging.Decode(image.Data)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("Saving %s image for date %s without renditions: %v", image.Source, image.Date, err)
+			image.ImageInfo = model.ImageInfo{Checksum: imaging.Checksum(image.Data)}
+		} else {
+			image.ImageInfo = imaging.Analyze(image.Data, decoded, format)
+			image.Renditions, err = imaging.Generate(decoded, imaging.Specs)
+			if err != nil {
+				logging.FromContext(ctx).Warnf("Saving %s image for date %s without renditions: %v", image.Source, image.Date, err)
+			}
+		}
+	}
+	if len(image.HDData) > 0 {
+		image.HDChecksum = imaging.Checksum(image.HDData)
+	}
+
+	var uploaded []string
+	if is.blobStore != nil {
+		var err error
+		uploaded, err = is.putBlobs(ctx, image)
+		if err != nil {
+			is.deleteBlobs(ctx, uploaded)
+			return err
+		}
+	}
+
+	err := is.imageManager.Create(ctx, image)
+	if err != nil {
+		is.deleteBlobs(ctx, uploaded)
+	}
+	if errors.Is(err, repository.ErrDuplicate) {
+		return Conflict("%s image for date %s is already stored", image.Source, image.Date)
+	}
+	return err
+}
+
+// GetByDate retrieves an image of the source from the database by the specified date.
+// It returns a NotFound error if no image of the source is stored for the date.
+func (is *imageService) GetByDate(ctx context.Context, source, date string) (*model.Image, error) {
+	image, err := is.getByDate(ctx, source, date)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := is.loadData(ctx, image); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// GetHDDataByDate retrieves the high definition rendition of an image of the source by the specified date.
+// It returns a NotFound error if the image or its high definition rendition is not stored.
+func (is *imageService) GetHDDataByDate(ctx context.Context, source, date string) ([]byte, error) {
+	image, err := is.getByDate(ctx, source, date)
+	if err != nil {
+		return nil, err
+	}
+	if image.HDBlobKey != "" {
+		return is.getBlob(ctx, image.HDBlobKey)
+	}
+
+	data, err := is.imageManager.GetHDDataByDate(ctx, source, date)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && len(data) == 0) {
+		return nil, NotFound("no HD rendition of the %s image for date %s", source, date)
+	}
+	return data, err
 }
 
-// GetByDate retrieves an image from the database by the specified date.
-func (is *imageService) GetByDate(date string) (*model.Image, error) {
-	return is.imageManager.GetByDate(date)
+// getByDate retrieves the stored image of the source for the date without loading its data.
+func (is *imageService) getByDate(ctx context.Context, source, date string) (*model.Image, error) {
+	image, err := is.imageManager.GetByDate(ctx, source, date)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, NotFound("no %s image for date %s", source, date)
+	}
+	return image, err
 }
 
 // GetAll retrieves all images from the database.
-func (is *imageService) GetAll() ([]*model.Image, error) {
-	return is.imageManager.GetAll()
+func (is *imageService) GetAll(ctx context.Context) ([]*model.Image, error) {
+	images, err := is.imageManager.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		if err := is.loadData(ctx, image); err != nil {
+			return nil, err
+		}
+	}
+	return images, nil
+}
+
+// GetDates retrieves the dates of all stored images of the source within the inclusive range [from, to].
+func (is *imageService) GetDates(ctx context.Context, source, from, to string) ([]string, error) {
+	return is.imageManager.GetDates(ctx, source, from, to)
+}
+
+// GetRandomDate retrieves the date of a randomly chosen stored image of the source.
+// It returns a NotFound error if no image of the source is stored.
+func (is *imageService) GetRandomDate(ctx context.Context, source string) (string, error) {
+	date, err := is.imageManager.GetRandomDate(ctx, source)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", NotFound("no %s images are stored", source)
+	}
+	return date, err
+}
+
+// List retrieves image metadata from the database according to the given options.
+func (is *imageService) List(ctx context.Context, opts model.ListOptions) ([]*model.ImageMetadata, error) {
+	return is.imageManager.List(ctx, opts)
+}
+
+// GetRendition retrieves the named rendition of an image of the source by the specified date.
+// It returns a NotFound error if the image or the rendition is not stored.
+func (is *imageService) GetRendition(ctx context.Context, source, date, name string) (*model.Rendition, error) {
+	rendition, err := is.imageManager.GetRendition(ctx, source, date, name)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, NotFound("no %s rendition of the %s image for date %s", name, source, date)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rendition.BlobKey != "" {
+		rendition.Data, err = is.getBlob(ctx, rendition.BlobKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rendition, nil
+}
+
+// Search retrieves metadata of the images whose title or explanation matches the query, best ranked first.
+func (is *imageService) Search(ctx context.Context, opts model.SearchOptions) ([]*model.SearchResult, error) {
+	if strings.TrimSpace(opts.Query) == "" {
+		return nil, InvalidInput("search query is required")
+	}
+	return is.imageManager.Search(ctx, opts)
+}
+
+// GetStats counts the stored images and the bytes of their data.
+func (is *imageService) GetStats(ctx context.Context) (*model.StorageStats, error) {
+	return is.imageManager.GetStats(ctx)
+}
+
+// MigrateBlobs moves image data still stored in the database into the blob store,
+// batchSize blobs at a time, and returns the number of migrated blobs.
+// Data shared by several images is migrated once.
+func (is *imageService) MigrateBlobs(ctx context.Context, batchSize int) (int, error) {
+	if is.blobStore == nil {
+		return 0, errNoBlobStore
+	}
+
+	migrated := 0
+	for {
+		blobs, err := is.imageManager.GetDatabaseBlobs(ctx, batchSize)
+		if err != nil {
+			return migrated, err
+		}
+		if len(blobs) == 0 {
+			return migrated, nil
+		}
+
+		for _, blob := range blobs {
+			key := contentKey(blob.Checksum)
+			if err := is.blobStore.Put(ctx, key, blob.Data); err != nil {
+				return migrated, fmt.Errorf("migrate blob %s: %w", blob.Checksum, err)
+			}
+			if err := is.imageManager.SetBlobKey(ctx, blob.Checksum, key); err != nil {
+				return migrated, fmt.Errorf("migrate blob %s: %w", blob.Checksum, err)
+			}
+			migrated++
+		}
+	}
+}
+
+// GenerateRenditions generates the renditions of the stored images that have none,
+// batchSize images at a time, and returns the number of images renditions were generated for.
+// Images whose data cannot be decoded are skipped.
+func (is *imageService) GenerateRenditions(ctx context.Context, batchSize int) (int, error) {
+	generated := 0
+	after := uuid.Nil
+	for {
+		images, err := is.imageManager.GetWithoutRenditions(ctx, after, batchSize)
+		if err != nil {
+			return generated, err
+		}
+		if len(images) == 0 {
+			return generated, nil
+		}
+
+		for _, image := range images {
+			after = image.ID
+			if err := ctx.Err(); err != nil {
+				return generated, err
+			}
+			if err := is.loadData(ctx, image); err != nil {
+				return generated, fmt.Errorf("generate renditions of %s image for date %s: %w", image.Source, image.Date, err)
+			}
+
+			decoded, _, err := imaging.Decode(image.Data)
+			if err == nil {
+				image.Renditions, err = imaging.Generate(decoded, imaging.Specs)
+			}
+			if err != nil {
+				logging.FromContext(ctx).Warnf("Skipping renditions of %s image for date %s: %v", image.Source, image.Date, err)
+				continue
+			}
+			var uploaded []string
+			if is.blobStore != nil {
+				uploaded, err = is.putRenditionBlobs(ctx, image)
+				if err != nil {
+					is.deleteBlobs(ctx, uploaded)
+					return generated, fmt.Errorf("generate renditions of %s image for date %s: %w", image.Source, image.Date, err)
+				}
+			}
+			if err := is.imageManager.CreateRenditions(ctx, image.ID, image.Renditions); err != nil {
+				is.deleteBlobs(ctx, uploaded)
+				return generated, fmt.Errorf("generate renditions of %s image for date %s: %w", image.Source, image.Date, err)
+			}
+			generated++
+		}
+	}
+}
+
+// putBlobs uploads the data of the image to the blob store, replaces it with blob keys and returns the keys
+// of the uploaded renditions, including those uploaded before an error. Data that is already stored under
+// its checksum is not uploaded again.
+//
+// The keys of the data are not returned: they are shared by every image with the same checksum, and another
+// image may be stored with them while this one is not, so they are never deleted on failure. An unreferenced
+// data blob is left to be swept from the blob store.
+func (is *imageService) putBlobs(ctx context.Context, image *model.Image) ([]string, error) {
+	if len(image.Data) > 0 {
+		key, err := is.putContent(ctx, image.Checksum, image.Data)
+		if err != nil {
+			return nil, err
+		}
+		image.BlobKey = key
+		image.Data = nil
+	}
+
+	if len(image.HDData) > 0 {
+		key, err := is.putContent(ctx, image.HDChecksum, image.HDData)
+		if err != nil {
+			return nil, err
+		}
+		image.HDBlobKey = key
+		image.HDData = nil
+	}
+
+	return is.putRenditionBlobs(ctx, image)
+}
+
+// putContent uploads the data with the checksum to the blob store unless it is already stored,
+// and returns its blob key.
+func (is *imageService) putContent(ctx context.Context, checksum string, data []byte) (string, error) {
+	key := contentKey(checksum)
+	stored, err := is.imageManager.HasBlob(ctx, checksum)
+	if err != nil {
+		return "", err
+	}
+	if stored {
+		return key, nil
+	}
+
+	if err := is.blobStore.Put(ctx, key, data); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// putRenditionBlobs uploads the data of the renditions of the image to the blob store, replaces it with
+// blob keys and returns the keys of the uploaded blobs.
+func (is *imageService) putRenditionBlobs(ctx context.Context, image *model.Image) ([]string, error) {
+	uploaded := make([]string, 0, len(image.Renditions))
+	for _, rendition := range image.Renditions {
+		key := blobKey(image.ID, rendition.Name)
+		if err := is.blobStore.Put(ctx, key, rendition.Data); err != nil {
+			return uploaded, err
+		}
+		uploaded = append(uploaded, key)
+		rendition.BlobKey = key
+		rendition.Data = nil
+	}
+	return uploaded, nil
+}
+
+// deleteBlobs deletes the renditions uploaded for an image that was not stored, so that no object is left
+// that no row refers to. Failures are only logged, as they leave nothing worse than an unreferenced object.
+func (is *imageService) deleteBlobs(ctx context.Context, keys []string) {
+	// The image may not have been stored because the context is done; the blobs are deleted regardless.
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if err := is.blobStore.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Warnf("Error deleting unreferenced blob %s: %v", key, err)
+		}
+	}
+}
+
+// loadData loads the image data from the blob store if it is not kept in the database.
+func (is *imageService) loadData(ctx context.Context, image *model.Image) error {
+	if image.BlobKey == "" {
+		return nil
+	}
+
+	data, err := is.getBlob(ctx, image.BlobKey)
+	if err != nil {
+		return err
+	}
+	image.Data = data
+	return nil
+}
+
+func (is *imageService) getBlob(ctx context.Context, key string) ([]byte, error) {
+	if is.blobStore == nil {
+		return nil, errNoBlobStore
+	}
+
+	data, err := is.blobStore.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("get blob %s: %w", key, err)
+	}
+	if err != nil {
+		return nil, Unavailable(err, "blob store is unavailable")
+	}
+	return data, nil
+}
+
+// contentKey returns the blob store key of the data with the checksum.
+func contentKey(checksum string) string {
+	return "blobs/" + checksum
+}
+
+// blobKey returns the blob store key of a rendition of an image.
+func blobKey(id uuid.UUID, rendition string) string {
+	return "images/" + id.String() + "/" + rendition
 }
@@ -1,31 +1,139 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	"github.com/EgMeln/YoungAstrologer/internal/imaging"
 	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+	"github.com/EgMeln/YoungAstrologer/internal/storage"
 )
 
 type mockImageManager struct {
-	CreateFunc    func(image *model.Image) error
-	GetByDateFunc func(date string) (*model.Image, error)
-	GetAllFunc    func() ([]*model.Image, error)
+	CreateFunc           func(image *model.Image) error
+	GetByDateFunc        func(source, date string) (*model.Image, error)
+	GetHDDataByDateFunc  func(source, date string) ([]byte, error)
+	GetAllFunc           func() ([]*model.Image, error)
+	GetDatesFunc         func(source, from, to string) ([]string, error)
+	GetRandomDateFunc    func(source string) (string, error)
+	ListFunc             func(opts model.ListOptions) ([]*model.ImageMetadata, error)
+	SearchFunc           func(opts model.SearchOptions) ([]*model.SearchResult, error)
+	HasBlobFunc          func(checksum string) (bool, error)
+	GetDatabaseBlobsFunc func(limit int) ([]*model.Blob, error)
+	SetBlobKeyFunc       func(checksum, blobKey string) error
+
+	GetRenditionFunc         func(source, date, name string) (*model.Rendition, error)
+	GetWithoutRenditionsFunc func(after uuid.UUID, limit int) ([]*model.Image, error)
+	CreateRenditionsFunc     func(imageID uuid.UUID, renditions []*model.Rendition) error
+	GetStatsFunc             func() (*model.StorageStats, error)
 }
 
-func (m *mockImageManager) Create(image *model.Image) error {
+func (m *mockImageManager) Create(ctx context.Context, image *model.Image) error {
 	return m.CreateFunc(image)
 }
 
-func (m *mockImageManager) GetByDate(date string) (*model.Image, error) {
-	return m.GetByDateFunc(date)
+func (m *mockImageManager) GetByDate(ctx context.Context, source, date string) (*model.Image, error) {
+	return m.GetByDateFunc(source, date)
+}
+
+func (m *mockImageManager) GetHDDataByDate(ctx context.Context, source, date string) ([]byte, error) {
+	return m.GetHDDataByDateFunc(source, date)
 }
 
-func (m *mockImageManager) GetAll() ([]*model.Image, error) {
+func (m *mockImageManager) GetAll(ctx context.Context) ([]*model.Image, error) {
 	return m.GetAllFunc()
 }
+
+func (m *mockImageManager) GetDates(ctx context.Context, source, from, to string) ([]string, error) {
+	return m.GetDatesFunc(source, from, to)
+}
+
+func (m *mockImageManager) GetRandomDate(ctx context.Context, source string) (string, error) {
+	return m.GetRandomDateFunc(source)
+}
+
+func (m *mockImageManager) List(ctx context.Context, opts model.ListOptions) ([]*model.ImageMetadata, error) {
+	return m.ListFunc(opts)
+}
+
+func (m *mockImageManager) Search(ctx context.Context, opts model.SearchOptions) ([]*model.SearchResult, error) {
+	return m.SearchFunc(opts)
+}
+
+func (m *mockImageManager) HasBlob(ctx context.Context, checksum string) (bool, error) {
+	return m.HasBlobFunc(checksum)
+}
+
+func (m *mockImageManager) GetDatabaseBlobs(ctx context.Context, limit int) ([]*model.Blob, error) {
+	return m.GetDatabaseBlobsFunc(limit)
+}
+
+func (m *mockImageManager) SetBlobKey(ctx context.Context, checksum, blobKey string) error {
+	return m.SetBlobKeyFunc(checksum, blobKey)
+}
+
+func (m *mockImageManager) GetRendition(ctx context.Context, source, date, name string) (*model.Rendition, error) {
+	return m.GetRenditionFunc(source, date, name)
+}
+
+func (m *mockImageManager) GetWithoutRenditions(ctx context.Context, after uuid.UUID, limit int) ([]*model.Image, error) {
+	return m.GetWithoutRenditionsFunc(after, limit)
+}
+
+func (m *mockImageManager) CreateRenditions(ctx context.Context, imageID uuid.UUID, renditions []*model.Rendition) error {
+	return m.CreateRenditionsFunc(imageID, renditions)
+}
+
+func (m *mockImageManager) GetStats(ctx context.Context) (*model.StorageStats, error) {
+	return m.GetStatsFunc()
+}
+
+type mockBlobStore struct {
+	blobs map[string][]byte
+}
+
+func (m *mockBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	m.blobs[key] = data
+	return nil
+}
+
+func (m *mockBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return data, nil
+}
+
+func (m *mockBlobStore) Delete(ctx context.Context, key string) error {
+	delete(m.blobs, key)
+	return nil
+}
+
+type failingBlobStore struct {
+	err error
+}
+
+func (m *failingBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	return m.err
+}
+
+func (m *failingBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, m.err
+}
+
+func (m *failingBlobStore) Delete(ctx context.Context, key string) error {
+	return m.err
+}
+
 func TestImageService_Save(t *testing.T) {
 	t.Parallel()
 
@@ -35,7 +143,7 @@ func TestImageService_Save(t *testing.T) {
 		},
 	}
 
-	imageSvc := NewImageService(mockManager)
+	imageSvc := NewImageService(mockManager, nil)
 
 	image := &model.Image{
 		Date:        "2024-05-18",
@@ -45,16 +153,17 @@ func TestImageService_Save(t *testing.T) {
 		Data:        []byte{0x89, 0x50, 0x4E, 0x47},
 	}
 
-	err := imageSvc.Save(image)
+	err := imageSvc.Save(context.Background(), image)
 	require.NoError(t, err)
 	require.NotEqual(t, uuid.Nil, image.ID)
+	require.Equal(t, model.DefaultSource, image.Source)
 }
 
 func TestImageService_GetByDate(t *testing.T) {
 	t.Parallel()
 
 	mockManager := &mockImageManager{
-		GetByDateFunc: func(date string) (*model.Image, error) {
+		GetByDateFunc: func(source, date string) (*model.Image, error) {
 			if date == "2024-05-18" {
 				return &model.Image{
 					ID:          uuid.New(),
@@ -65,16 +174,20 @@ func TestImageService_GetByDate(t *testing.T) {
 					Data:        []byte{0x89, 0x50, 0x4E, 0x47},
 				}, nil
 			}
-			return nil, nil
+			return nil, repository.ErrNotFound
 		},
 	}
 
-	imageSvc := NewImageService(mockManager)
+	imageSvc := NewImageService(mockManager, nil)
 
-	image, err := imageSvc.GetByDate("2024-05-18")
+	image, err := imageSvc.GetByDate(context.Background(), model.DefaultSource, "2024-05-18")
 	require.NoError(t, err)
 	require.NotNil(t, image)
 	require.Equal(t, "2024-05-18", image.Date)
+
+	_, err = imageSvc.GetByDate(context.Background(), model.DefaultSource, "2024-05-19")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NotErrorIs(t, err, repository.ErrNotFound)
 }
 
 func TestImageService_GetAll(t *testing.T) {
@@ -103,9 +216,396 @@ func TestImageService_GetAll(t *testing.T) {
 		},
 	}
 
-	imageSvc := NewImageService(mockManager)
+	imageSvc := NewImageService(mockManager, nil)
 
-	images, err := imageSvc.GetAll()
+	images, err := imageSvc.GetAll(context.Background())
 	require.NoError(t, err)
 	require.Len(t, images, 2)
 }
+
+func TestImageService_GetDates(t *testing.T) {
+	t.Parallel()
+
+	mockManager := &mockImageManager{
+		GetDatesFunc: func(source, from, to string) ([]string, error) {
+			require.Equal(t, model.DefaultSource, source)
+			require.Equal(t, "2024-05-01", from)
+			require.Equal(t, "2024-05-31", to)
+			return []string{"2024-05-18", "2024-05-19"}, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, nil)
+
+	dates, err := imageSvc.GetDates(context.Background(), model.DefaultSource, "2024-05-01", "2024-05-31")
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-05-18", "2024-05-19"}, dates)
+}
+
+func TestImageService_GetRandomDate(t *testing.T) {
+	t.Parallel()
+
+	mockManager := &mockImageManager{
+		GetRandomDateFunc: func(source string) (string, error) {
+			if source == "drop" {
+				return "", repository.ErrNotFound
+			}
+			return "2024-05-18", nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, nil)
+
+	date, err := imageSvc.GetRandomDate(context.Background(), model.DefaultSource)
+	require.NoError(t, err)
+	require.Equal(t, "2024-05-18", date)
+
+	_, err = imageSvc.GetRandomDate(context.Background(), "drop")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestImageService_List(t *testing.T) {
+	t.Parallel()
+
+	opts := model.ListOptions{Limit: 10, After: "2024-05-17", Order: model.SortAsc}
+	mockManager := &mockImageManager{
+		ListFunc: func(o model.ListOptions) ([]*model.ImageMetadata, error) {
+			require.Equal(t, opts, o)
+			return []*model.ImageMetadata{
+				{ID: uuid.New(), Date: "2024-05-18", Title: "A Beautiful Nebula", MediaType: "image", Size: 4},
+			}, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, nil)
+
+	images, err := imageSvc.List(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	require.Equal(t, "2024-05-18", images[0].Date)
+}
+
+func TestImageService_Search(t *testing.T) {
+	t.Parallel()
+
+	opts := model.SearchOptions{Source: model.DefaultSource, Query: "andromeda", Limit: 10}
+	mockManager := &mockImageManager{
+		SearchFunc: func(o model.SearchOptions) ([]*model.SearchResult, error) {
+			require.Equal(t, opts, o)
+			return []*model.SearchResult{
+				{ImageMetadata: &model.ImageMetadata{Date: "2024-05-17", Title: "The Andromeda Galaxy"}, Rank: 0.5},
+			}, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, nil)
+
+	results, err := imageSvc.Search(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "2024-05-17", results[0].Date)
+
+	_, err = imageSvc.Search(context.Background(), model.SearchOptions{Query: "  "})
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestImageService_GetHDDataByDate(t *testing.T) {
+	t.Parallel()
+
+	mockManager := &mockImageManager{
+		GetByDateFunc: func(source, date string) (*model.Image, error) {
+			if date == "2024-05-20" {
+				return nil, repository.ErrNotFound
+			}
+			return &model.Image{Date: date}, nil
+		},
+		GetHDDataByDateFunc: func(source, date string) ([]byte, error) {
+			if date == "2024-05-19" {
+				return nil, nil
+			}
+			return []byte{0x89, 0x50, 0x4E, 0x47}, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, nil)
+
+	data, err := imageSvc.GetHDDataByDate(context.Background(), model.DefaultSource, "2024-05-18")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x89, 0x50, 0x4E, 0x47}, data)
+
+	for _, date := range []string{"2024-05-19", "2024-05-20"} {
+		_, err = imageSvc.GetHDDataByDate(context.Background(), model.DefaultSource, date)
+		require.ErrorIs(t, err, ErrNotFound, date)
+	}
+}
+
+func TestImageService_BlobStoreUnavailable(t *testing.T) {
+	t.Parallel()
+
+	mockManager := &mockImageManager{
+		GetByDateFunc: func(source, date string) (*model.Image, error) {
+			return &model.Image{Date: date, BlobKey: "images/original"}, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, &failingBlobStore{err: errors.New("connection refused")})
+
+	_, err := imageSvc.GetByDate(context.Background(), model.DefaultSource, "2024-05-18")
+	require.ErrorIs(t, err, ErrUnavailable)
+	require.ErrorContains(t, err, "connection refused")
+}
+
+func TestImageService_BlobStore(t *testing.T) {
+	t.Parallel()
+
+	blobStore := &mockBlobStore{blobs: make(map[string][]byte)}
+	var created *model.Image
+	mockManager := &mockImageManager{
+		CreateFunc: func(image *model.Image) error {
+			created = image
+			return nil
+		},
+		GetByDateFunc: func(source, date string) (*model.Image, error) {
+			image := *created
+			return &image, nil
+		},
+		HasBlobFunc: func(checksum string) (bool, error) {
+			_, ok := blobStore.blobs["blobs/"+checksum]
+			return ok, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, blobStore)
+
+	err := imageSvc.Save(context.Background(), &model.Image{
+		Date:      "2024-05-18",
+		MediaType: "image",
+		Data:      []byte{0x89, 0x50, 0x4E, 0x47},
+		HDData:    []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A},
+	})
+	require.NoError(t, err)
+	require.Nil(t, created.Data)
+	require.Nil(t, created.HDData)
+	require.Equal(t, int64(4), created.Size)
+	require.Equal(t, int64(6), created.HDSize)
+	require.Equal(t, "blobs/"+created.Checksum, created.BlobKey)
+	require.Equal(t, "blobs/"+created.HDChecksum, created.HDBlobKey)
+	require.Len(t, blobStore.blobs, 2)
+
+	image, err := imageSvc.GetByDate(context.Background(), model.DefaultSource, "2024-05-18")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x89, 0x50, 0x4E, 0x47}, image.Data)
+
+	hdData, err := imageSvc.GetHDDataByDate(context.Background(), model.DefaultSource, "2024-05-18")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A}, hdData)
+
+	err = imageSvc.Save(context.Background(), &model.Image{Date: "2024-05-19", MediaType: "image", Data: []byte{0x89, 0x50, 0x4E, 0x47}})
+	require.NoError(t, err)
+	require.Equal(t, "blobs/"+created.Checksum, created.BlobKey)
+	require.Len(t, blobStore.blobs, 2)
+
+	delete(blobStore.blobs, created.BlobKey)
+	_, err = imageSvc.GetByDate(context.Background(), model.DefaultSource, "2024-05-18")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestImageService_MigrateBlobs(t *testing.T) {
+	t.Parallel()
+
+	blobs := []*model.Blob{
+		{Checksum: "checksum-1", Data: []byte{0x89, 0x50, 0x4E, 0x47}, Size: 4},
+		{Checksum: "checksum-2", Data: []byte{0x47, 0x49, 0x46}, Size: 3},
+		{Checksum: "checksum-3", Data: []byte{0xFF, 0xD8, 0xFF}, Size: 3},
+	}
+	keys := make(map[string]string)
+	mockManager := &mockImageManager{
+		GetDatabaseBlobsFunc: func(limit int) ([]*model.Blob, error) {
+			require.Equal(t, 2, limit)
+			var batch []*model.Blob
+			for _, blob := range blobs {
+				if _, ok := keys[blob.Checksum]; !ok && len(batch) < limit {
+					batch = append(batch, blob)
+				}
+			}
+			return batch, nil
+		},
+		SetBlobKeyFunc: func(checksum, blobKey string) error {
+			keys[checksum] = blobKey
+			return nil
+		},
+	}
+	blobStore := &mockBlobStore{blobs: make(map[string][]byte)}
+
+	imageSvc := NewImageService(mockManager, blobStore)
+
+	migrated, err := imageSvc.MigrateBlobs(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, 3, migrated)
+	require.Len(t, blobStore.blobs, 3)
+	require.Equal(t, "blobs/checksum-1", keys["checksum-1"])
+	require.Equal(t, blobs[2].Data, blobStore.blobs["blobs/checksum-3"])
+
+	_, err = NewImageService(mockManager, nil).MigrateBlobs(context.Background(), 2)
+	require.Error(t, err)
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestImageService_SaveRenditions(t *testing.T) {
+	t.Parallel()
+
+	blobStore := &mockBlobStore{blobs: make(map[string][]byte)}
+	var created *model.Image
+	mockManager := &mockImageManager{
+		CreateFunc: func(image *model.Image) error {
+			created = image
+			return nil
+		},
+		HasBlobFunc: func(checksum string) (bool, error) {
+			return false, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, blobStore)
+
+	err := imageSvc.Save(context.Background(), &model.Image{Date: "2024-05-18", MediaType: "image", Data: encodePNG(t, 2048, 1024)})
+	require.NoError(t, err)
+	require.Len(t, created.Renditions, 2)
+	for _, rendition := range created.Renditions {
+		require.Nil(t, rendition.Data)
+		require.Equal(t, "images/"+created.ID.String()+"/"+rendition.Name, rendition.BlobKey)
+		require.Len(t, blobStore.blobs[rendition.BlobKey], int(rendition.Size))
+	}
+	require.Equal(t, [2]int{320, 160}, [2]int{created.Renditions[0].Width, created.Renditions[0].Height})
+	require.Equal(t, "png", created.Format)
+	require.Equal(t, [2]int{2048, 1024}, [2]int{created.Width, created.Height})
+	require.Equal(t, "#000000", created.DominantColor)
+	require.Len(t, created.Checksum, 64)
+
+	err = imageSvc.Save(context.Background(), &model.Image{Date: "2024-05-19", MediaType: "image", Data: []byte("not an image")})
+	require.NoError(t, err)
+	require.Empty(t, created.Renditions)
+	require.Empty(t, created.Format)
+	require.Len(t, created.Checksum, 64)
+}
+
+func TestImageService_GetRendition(t *testing.T) {
+	t.Parallel()
+
+	blobStore := &mockBlobStore{blobs: map[string][]byte{"images/thumb": {0xFF, 0xD8}}}
+	mockManager := &mockImageManager{
+		GetRenditionFunc: func(source, date, name string) (*model.Rendition, error) {
+			if name != model.RenditionThumb {
+				return nil, repository.ErrNotFound
+			}
+			return &model.Rendition{Name: name, ContentType: "image/jpeg", Size: 2, BlobKey: "images/thumb"}, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, blobStore)
+
+	rendition, err := imageSvc.GetRendition(context.Background(), model.DefaultSource, "2024-05-18", model.RenditionThumb)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xFF, 0xD8}, rendition.Data)
+
+	_, err = imageSvc.GetRendition(context.Background(), model.DefaultSource, "2024-05-18", model.RenditionMedium)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestImageService_GenerateRenditions(t *testing.T) {
+	t.Parallel()
+
+	stored := []*model.Image{
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Date: "2024-05-18", Data: encodePNG(t, 640, 480)},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), Date: "2024-05-19", Data: []byte("not an image")},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), Date: "2024-05-20", Data: encodePNG(t, 100, 100)},
+	}
+	created := make(map[uuid.UUID][]*model.Rendition)
+	mockManager := &mockImageManager{
+		GetWithoutRenditionsFunc: func(after uuid.UUID, limit int) ([]*model.Image, error) {
+			var images []*model.Image
+			for _, image := range stored {
+				if image.ID.String() > after.String() && created[image.ID] == nil && len(images) < limit {
+					images = append(images, image)
+				}
+			}
+			return images, nil
+		},
+		CreateRenditionsFunc: func(imageID uuid.UUID, renditions []*model.Rendition) error {
+			created[imageID] = renditions
+			return nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, nil)
+
+	generated, err := imageSvc.GenerateRenditions(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, generated)
+	require.Len(t, created[stored[0].ID], 2)
+	require.NotEmpty(t, created[stored[0].ID][0].Data)
+	require.Nil(t, created[stored[1].ID])
+	require.Len(t, created[stored[2].ID], 2)
+}
+
+func TestImageService_GenerateRenditionsError(t *testing.T) {
+	t.Parallel()
+
+	blobStore := &mockBlobStore{blobs: make(map[string][]byte)}
+	mockManager := &mockImageManager{
+		GetWithoutRenditionsFunc: func(after uuid.UUID, limit int) ([]*model.Image, error) {
+			return []*model.Image{{ID: uuid.New(), Date: "2024-05-18", Data: encodePNG(t, 640, 480)}}, nil
+		},
+		CreateRenditionsFunc: func(imageID uuid.UUID, renditions []*model.Rendition) error {
+			return errors.New("connection refused")
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, blobStore)
+
+	generated, err := imageSvc.GenerateRenditions(context.Background(), 2)
+	require.Error(t, err)
+	require.Zero(t, generated)
+	require.Empty(t, blobStore.blobs)
+}
+
+func TestImageService_SaveDuplicate(t *testing.T) {
+	t.Parallel()
+
+	blobStore := &mockBlobStore{blobs: make(map[string][]byte)}
+	data := encodePNG(t, 2048, 1024)
+	hdData := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A}
+	mockManager := &mockImageManager{
+		CreateFunc: func(image *model.Image) error {
+			return repository.ErrDuplicate
+		},
+		HasBlobFunc: func(checksum string) (bool, error) {
+			return false, nil
+		},
+	}
+
+	imageSvc := NewImageService(mockManager, blobStore)
+
+	// The renditions belong to the image alone and are deleted; the data may be shared by a concurrently
+	// stored image with the same checksum and is kept.
+	err := imageSvc.Save(context.Background(), &model.Image{Date: "2024-05-18", MediaType: "image", Data: data, HDData: hdData})
+	require.ErrorIs(t, err, ErrConflict)
+	require.Equal(t, map[string][]byte{
+		"blobs/" + imaging.Checksum(data):   data,
+		"blobs/" + imaging.Checksum(hdData): hdData,
+	}, blobStore.blobs)
+
+	mockManager.CreateFunc = func(image *model.Image) error {
+		return errors.New("connection refused")
+	}
+	err = imageSvc.Save(context.Background(), &model.Image{Date: "2024-05-19", MediaType: "image", Data: encodePNG(t, 640, 480)})
+	require.Error(t, err)
+	require.Len(t, blobStore.blobs, 3)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/tracing"
+)
+
+// NewInstrumentedImageService returns an ImageService that traces every call to next in a span.
+func NewInstrumentedImageService(next ImageService) ImageService {
+	return &instrumentedImageService{
+		next: next,
+	}
+}
+
+type instrumentedImageService struct {
+	next ImageService
+}
+
+// endSpan ends the span of a call with its error. Errors caused by the request, such as a missing image
+// or an invalid parameter, are expected outcomes and do not fail the span.
+func endSpan(span trace.Span, err error) {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidInput) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+func (is *instrumentedImageService) Save(ctx context.Context, image *model.Image) (err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.Save")
+	defer func() { endSpan(span, err) }()
+	return is.next.Save(ctx, image)
+}
+
+func (is *instrumentedImageService) GetByDate(ctx context.Context, source, date string) (_ *model.Image, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GetByDate")
+	defer func() { endSpan(span, err) }()
+	return is.next.GetByDate(ctx, source, date)
+}
+
+func (is *instrumentedImageService) GetHDDataByDate(ctx context.Context, source, date string) (_ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GetHDDataByDate")
+	defer func() { endSpan(span, err) }()
+	return is.next.GetHDDataByDate(ctx, source, date)
+}
+
+func (is *instrumentedImageService) GetAll(ctx context.Context) (_ []*model.Image, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GetAll")
+	defer func() { endSpan(span, err) }()
+	return is.next.GetAll(ctx)
+}
+
+func (is *instrumentedImageService) GetDates(ctx context.Context, source, from, to string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GetDates")
+	defer func() { endSpan(span, err) }()
+	return is.next.GetDates(ctx, source, from, to)
+}
+
+func (is *instrumentedImageService) GetRandomDate(ctx context.Context, source string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GetRandomDate")
+	defer func() { endSpan(span, err) }()
+	return is.next.GetRandomDate(ctx, source)
+}
+
+func (is *instrumentedImageService) List(ctx context.Context, opts model.ListOptions) (_ []*model.ImageMetadata, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.List")
+	defer func() { endSpan(span, err) }()
+	return is.next.List(ctx, opts)
+}
+
+func (is *instrumentedImageService) Search(ctx context.Context, opts model.SearchOptions) (_ []*model.SearchResult, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.Search")
+	defer func() { endSpan(span, err) }()
+	return is.next.Search(ctx, opts)
+}
+
+func (is *instrumentedImageService) GetRendition(ctx context.Context, source, date, name string) (_ *model.Rendition, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GetRendition")
+	defer func() { endSpan(span, err) }()
+	return is.next.GetRendition(ctx, source, date, name)
+}
+
+func (is *instrumentedImageService) MigrateBlobs(ctx context.Context, batchSize int) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.MigrateBlobs")
+	defer func() { endSpan(span, err) }()
+	return is.next.MigrateBlobs(ctx, batchSize)
+}
+
+func (is *instrumentedImageService) GenerateRenditions(ctx context.Context, batchSize int) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GenerateRenditions")
+	defer func() { endSpan(span, err) }()
+	return is.next.GenerateRenditions(ctx, batchSize)
+}
+
+func (is *instrumentedImageService) GetStats(ctx context.Context) (_ *model.StorageStats, err error) {
+	ctx, span := tracing.Start(ctx, "ImageService.GetStats")
+	defer func() { endSpan(span, err) }()
+	return is.next.GetStats(ctx)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+)
+
+func TestInstrumentedImageService(t *testing.T) {
+	// The tracer of the service binds to the first provider registered, so a single one records every case.
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	tests := []struct {
+		name               string
+		getStatsErr        error
+		getByDateErr       error
+		expectedByDateErr  error
+		expectedStatsCode  codes.Code
+		expectedByDateCode codes.Code
+	}{
+		{
+			name:               "Success",
+			expectedStatsCode:  codes.Unset,
+			expectedByDateCode: codes.Unset,
+		},
+		{
+			name:               "NotFoundIsExpected",
+			getStatsErr:        errors.New("connection refused"),
+			getByDateErr:       repository.ErrNotFound,
+			expectedByDateErr:  ErrNotFound,
+			expectedStatsCode:  codes.Error,
+			expectedByDateCode: codes.Unset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageManager := &mockImageManager{
+				GetByDateFunc: func(source, date string) (*model.Image, error) {
+					if tt.getByDateErr != nil {
+						return nil, tt.getByDateErr
+					}
+					return &model.Image{Source: source, Date: date}, nil
+				},
+				GetStatsFunc: func() (*model.StorageStats, error) {
+					if tt.getStatsErr != nil {
+						return nil, tt.getStatsErr
+					}
+					return &model.StorageStats{}, nil
+				},
+			}
+			imageService := NewInstrumentedImageService(NewImageService(imageManager, nil))
+
+			ended := len(recorder.Ended())
+			_, err := imageService.GetByDate(context.Background(), model.DefaultSource, "2024-05-18")
+			require.ErrorIs(t, err, tt.expectedByDateErr)
+			_, err = imageService.GetStats(context.Background())
+			require.ErrorIs(t, err, tt.getStatsErr)
+
+			spans := recorder.Ended()[ended:]
+			require.Len(t, spans, 2)
+			require.Equal(t, "ImageService.GetByDate", spans[0].Name())
+			require.Equal(t, tt.expectedByDateCode, spans[0].Status().Code)
+			require.Equal(t, "ImageService.GetStats", spans[1].Name())
+			require.Equal(t, tt.expectedStatsCode, spans[1].Status().Code)
+		})
+	}
+}
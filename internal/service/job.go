@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+)
+
+// JobService defines the interface for the job run service.
+type JobService interface {
+	Record(ctx context.Context, run *model.JobRun) error
+	List(ctx context.Context, job string, limit int) ([]*model.JobRun, error)
+	HasSucceeded(ctx context.Context, job, source, apodDate string) (bool, error)
+	Latest(ctx context.Context, job, source, status string) (*model.JobRun, error)
+}
+
+// NewJobService returns a new instance of JobService.
+func NewJobService(jobRunManager repository.JobRunManager) JobService {
+	return &jobService{
+		jobRunManager: jobRunManager,
+	}
+}
+
+type jobService struct {
+	jobRunManager repository.JobRunManager
+}
+
+// Record generates a new UUID for the job run and stores it in the database.
+func (js *jobService) Record(ctx context.Context, run *model.JobRun) error {
+	run.ID = uuid.New()
+	return js.jobRunManager.Create(ctx, run)
+}
+
+// List retrieves the most recent runs of the specified job from the database.
+func (js *jobService) List(ctx context.Context, job string, limit int) ([]*model.JobRun, error) {
+	return js.jobRunManager.List(ctx, job, limit)
+}
+
+// HasSucceeded reports whether a successful run of the specified job was recorded for the source and APOD date.
+func (js *jobService) HasSucceeded(ctx context.Context, job, source, apodDate string) (bool, error) {
+	return js.jobRunManager.HasSucceeded(ctx, job, source, apodDate)
+}
+
+// Latest retrieves the most recently started run of the job of the source with the status.
+// Empty job and status values match every job and status. It returns a NotFound error if there is no such run.
+func (js *jobService) Latest(ctx context.Context, job, source, status string) (*model.JobRun, error) {
+	run, err := js.jobRunManager.Latest(ctx, job, source, status)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, NotFound("no matching runs of %s", source)
+	}
+	return run, err
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/repository"
+)
+
+type mockJobRunManager struct {
+	CreateFunc       func(run *model.JobRun) error
+	ListFunc         func(job string, limit int) ([]*model.JobRun, error)
+	HasSucceededFunc func(job, source, apodDate string) (bool, error)
+	LatestFunc       func(job, source, status string) (*model.JobRun, error)
+}
+
+func (m *mockJobRunManager) Create(ctx context.Context, run *model.JobRun) error {
+	return m.CreateFunc(run)
+}
+
+func (m *mockJobRunManager) List(ctx context.Context, job string, limit int) ([]*model.JobRun, error) {
+	return m.ListFunc(job, limit)
+}
+
+func (m *mockJobRunManager) HasSucceeded(ctx context.Context, job, source, apodDate string) (bool, error) {
+	return m.HasSucceededFunc(job, source, apodDate)
+}
+
+func (m *mockJobRunManager) Latest(ctx context.Context, job, source, status string) (*model.JobRun, error) {
+	return m.LatestFunc(job, source, status)
+}
+
+func TestJobService_Record(t *testing.T) {
+	t.Parallel()
+
+	mockManager := &mockJobRunManager{
+		CreateFunc: func(run *model.JobRun) error {
+			return nil
+		},
+	}
+
+	jobSvc := NewJobService(mockManager)
+
+	run := &model.JobRun{Job: "daily", Status: model.JobStatusSuccess, Attempts: 1}
+	err := jobSvc.Record(context.Background(), run)
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, run.ID)
+}
+
+func TestJobService_List(t *testing.T) {
+	t.Parallel()
+
+	mockManager := &mockJobRunManager{
+		ListFunc: func(job string, limit int) ([]*model.JobRun, error) {
+			require.Equal(t, "daily", job)
+			require.Equal(t, 10, limit)
+			return []*model.JobRun{{ID: uuid.New(), Job: "daily"}}, nil
+		},
+	}
+
+	jobSvc := NewJobService(mockManager)
+
+	runs, err := jobSvc.List(context.Background(), "daily", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+}
+
+func TestJobService_HasSucceeded(t *testing.T) {
+	t.Parallel()
+
+	mockManager := &mockJobRunManager{
+		HasSucceededFunc: func(job, source, apodDate string) (bool, error) {
+			return job == "daily" && source == model.DefaultSource && apodDate == "2024-05-18", nil
+		},
+	}
+
+	jobSvc := NewJobService(mockManager)
+
+	succeeded, err := jobSvc.HasSucceeded(context.Background(), "daily", model.DefaultSource, "2024-05-18")
+	require.NoError(t, err)
+	require.True(t, succeeded)
+}
+
+func TestJobService_Latest(t *testing.T) {
+	t.Parallel()
+
+	run := &model.JobRun{ID: uuid.New(), Job: "daily", Source: model.DefaultSource, Status: model.JobStatusSuccess}
+	mockManager := &mockJobRunManager{
+		LatestFunc: func(job, source, status string) (*model.JobRun, error) {
+			if source != model.DefaultSource {
+				return nil, repository.ErrNotFound
+			}
+			return run, nil
+		},
+	}
+
+	jobSvc := NewJobService(mockManager)
+
+	latest, err := jobSvc.Latest(context.Background(), "daily", model.DefaultSource, model.JobStatusSuccess)
+	require.NoError(t, err)
+	require.Equal(t, run, latest)
+
+	_, err = jobSvc.Latest(context.Background(), "daily", "drop", model.JobStatusSuccess)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+
+	data := []byte{0x89, 0x50, 0x4E, 0x47}
+	require.NoError(t, store.Put(context.Background(), "images/2024-05-18/original", data))
+
+	stored, err := os.ReadFile(filepath.Join(dir, "images", "2024-05-18", "original"))
+	require.NoError(t, err)
+	require.Equal(t, data, stored)
+
+	retrieved, err := store.Get(context.Background(), "images/2024-05-18/original")
+	require.NoError(t, err)
+	require.Equal(t, data, retrieved)
+
+	require.NoError(t, store.Delete(context.Background(), "images/2024-05-18/original"))
+	require.NoError(t, store.Delete(context.Background(), "images/2024-05-18/original"))
+
+	_, err = store.Get(context.Background(), "images/2024-05-18/original")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileStore_InvalidKey(t *testing.T) {
+	t.Parallel()
+
+	store := NewFileStore(t.TempDir())
+
+	for _, key := range []string{"", "../outside", "/etc/passwd", "images/../../outside"} {
+		require.Error(t, store.Put(context.Background(), key, []byte{0x00}), key)
+	}
+}
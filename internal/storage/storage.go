@@ -0,0 +1,17 @@
+// Package storage provides interfaces and implementations for storing image blobs outside of the database.
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when a blob with the requested key does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore defines the interface for storing binary objects by key.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,115 @@
+// Package tracing provides the OpenTelemetry spans of the service. Spans are exported by the tracer
+// provider registered with otel.SetTracerProvider; until one is registered, they are not recorded.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/EgMeln/YoungAstrologer/internal/httpserver"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+)
+
+// instrumentationName identifies the spans of the service.
+const instrumentationName = "github.com/EgMeln/YoungAstrologer"
+
+var tracer = otel.Tracer(instrumentationName)
+
+// Start starts a span with the attributes as a child of the span of ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End marks the span as failed with err, unless err is nil, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Handler wraps next so that every request is served in a span named after the route it matches, which must
+// have been resolved by httpserver.WithRoute.
+// The trace context of the request headers is continued, and the trace id is added to the logger of the
+// request context so the log entries of a request can be found from its trace.
+func Handler(next http.Handler) http.Handler {
+	withTraceID := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+			r = r.WithContext(logging.WithFields(r.Context(), log.Fields{"trace_id": spanContext.TraceID().String()}))
+		}
+		next.ServeHTTP(w, r)
+	})
+
+	return otelhttp.NewHandler(withTraceID, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return httpserver.Route(r)
+		}),
+	)
+}
+
+// Transport wraps next so that every outbound request is made in a span and carries the trace context
+// in its headers. The values of the query parameters holding secrets are redacted from the URL recorded
+// in the span.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return &redactingTransport{
+		next: otelhttp.NewTransport(&restoringTransport{next: next},
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method + " " + r.URL.Host
+			}),
+		),
+	}
+}
+
+// redactedParams lists the query parameters whose values are replaced in the URLs recorded in spans.
+var redactedParams = []string{"api_key"}
+
+// redactedValue replaces the values of the redacted query parameters.
+const redactedValue = "REDACTED"
+
+type originalURLKey struct{}
+
+// redactingTransport hands the traced transport a copy of the request whose URL has the secrets
+// redacted, along with the original URL, which restoringTransport sends the request to.
+type redactingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *redactingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query()
+	redacted := false
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, redactedValue)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return t.next.RoundTrip(req)
+	}
+
+	clone := req.Clone(context.WithValue(req.Context(), originalURLKey{}, req.URL))
+	clone.URL.RawQuery = query.Encode()
+	return t.next.RoundTrip(clone)
+}
+
+// restoringTransport sends the requests redacted by redactingTransport to their original URL.
+type restoringTransport struct {
+	next http.RoundTripper
+}
+
+func (t *restoringTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if original, ok := req.Context().Value(originalURLKey{}).(*url.URL); ok {
+		req = req.Clone(req.Context())
+		req.URL = original
+	}
+	return t.next.RoundTrip(req)
+}
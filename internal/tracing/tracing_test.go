@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/EgMeln/YoungAstrologer/internal/httpserver"
+	"github.com/EgMeln/YoungAstrologer/internal/logging"
+)
+
+// recorder records the spans ended by every test of the package.
+var recorder = tracetest.NewSpanRecorder()
+
+func init() {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// endedSpan returns the ended span with the trace id and name.
+func endedSpan(t *testing.T, traceID trace.TraceID, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == traceID && span.Name() == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not found", "no span %q in trace %s", name, traceID)
+	return nil
+}
+
+func TestEnd(t *testing.T) {
+	t.Parallel()
+
+	ctx, span := Start(context.Background(), "succeeded")
+	End(span, nil)
+	require.Equal(t, codes.Unset, endedSpan(t, trace.SpanContextFromContext(ctx).TraceID(), "succeeded").Status().Code)
+
+	ctx, span = Start(context.Background(), "failed")
+	End(span, errors.New("connection refused"))
+	failed := endedSpan(t, trace.SpanContextFromContext(ctx).TraceID(), "failed")
+	require.Equal(t, codes.Error, failed.Status().Code)
+	require.Equal(t, "connection refused", failed.Status().Description)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+
+	var logTraceID any
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /images/{date}", func(w http.ResponseWriter, r *http.Request) {
+		logTraceID = logging.FromContext(r.Context()).Data["trace_id"]
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedSpan string
+	}{
+		{name: "Matched", path: "/images/2024-05-18", expectedSpan: "GET /images/{date}"},
+		{name: "Unmatched", path: "/videos", expectedSpan: httpserver.UnmatchedRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+			httpserver.WithRoute(mux, Handler(mux)).ServeHTTP(httptest.NewRecorder(), req)
+
+			id, err := trace.TraceIDFromHex(traceID)
+			require.NoError(t, err)
+			span := endedSpan(t, id, tt.expectedSpan)
+			require.Equal(t, parentID, span.Parent().SpanID().String())
+		})
+	}
+
+	require.Equal(t, traceID, logTraceID)
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, span := Start(context.Background(), "fetch")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: Transport(http.DefaultTransport)}).Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	End(span, nil)
+
+	traceID := span.SpanContext().TraceID()
+	client := endedSpan(t, traceID, "GET "+req.URL.Host)
+	require.Equal(t, span.SpanContext().SpanID(), client.Parent().SpanID())
+	require.Equal(t, "00-"+traceID.String()+"-"+client.SpanContext().SpanID().String()+"-01", traceparent)
+}
+
+func TestTransport_RedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	var apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.URL.Query().Get("api_key")
+	}))
+	defer server.Close()
+
+	ctx, span := Start(context.Background(), "fetch")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/planetary/apod?api_key=SECRET_KEY&date=2024-05-18", nil)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: Transport(http.DefaultTransport)}).Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	End(span, nil)
+
+	require.Equal(t, "SECRET_KEY", apiKey)
+	require.Equal(t, server.URL+"/planetary/apod?api_key=SECRET_KEY&date=2024-05-18", req.URL.String())
+
+	client := endedSpan(t, span.SpanContext().TraceID(), "GET "+req.URL.Host)
+	var recordedURL string
+	for _, attr := range client.Attributes() {
+		require.NotContains(t, attr.Value.Emit(), "SECRET_KEY", "attribute %s", attr.Key)
+		if attr.Key == "http.url" || attr.Key == "url.full" {
+			recordedURL = attr.Value.AsString()
+		}
+	}
+	require.Contains(t, recordedURL, "api_key=REDACTED")
+	require.Contains(t, recordedURL, "date=2024-05-18")
+}
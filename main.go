@@ -1,97 +1,586 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gopkg.in/natefinch/lumberjack.v2"
 
+	"github.com/EgMeln/YoungAstrologer/internal/apodstub"
+	"github.com/EgMeln/YoungAstrologer/internal/config"
 	"github.com/EgMeln/YoungAstrologer/internal/handler"
+	"github.com/EgMeln/YoungAstrologer/internal/httpclient"
+	"github.com/EgMeln/YoungAstrologer/internal/httpserver"
+	"github.com/EgMeln/YoungAstrologer/internal/job"
+	"github.com/EgMeln/YoungAstrologer/internal/metrics"
+	"github.com/EgMeln/YoungAstrologer/internal/model"
+	"github.com/EgMeln/YoungAstrologer/internal/provider"
+	"github.com/EgMeln/YoungAstrologer/internal/ratelimit"
 	"github.com/EgMeln/YoungAstrologer/internal/repository"
 	"github.com/EgMeln/YoungAstrologer/internal/service"
+	"github.com/EgMeln/YoungAstrologer/internal/storage"
+	"github.com/EgMeln/YoungAstrologer/internal/tracing"
 )
 
+// version is the build version reported by the status endpoint, set with -ldflags "-X main.version=...".
+var version = "dev"
+
+// pingTimeout bounds how long the database is waited for on startup.
+const pingTimeout = 10 * time.Second
+
+// flushTimeout bounds how long the remaining spans are exported for on exit.
+const flushTimeout = 5 * time.Second
+
+// serviceName identifies the service in the exported spans.
+const serviceName = "young-astrologer"
+
+// catchUpDays is the number of days before the latest entry that each daily run checks for missing entries.
+const catchUpDays = 30
+
 func main() {
-	log.SetLevel(log.InfoLevel)
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := flags.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] [backfill|migrate-blobs|generate-renditions|api-keys|stub-apod [command flags]]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
 
-	logFile, err := os.OpenFile("logs.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	cfg, err := config.Load(flags, os.Args[1:], os.Getenv)
 	if err != nil {
-		log.Fatalf("Error opening log file: %v", err)
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(2)
 	}
-	defer logFile.Close()
 
-	log.SetOutput(logFile)
+	// The stub server runs offline and needs neither the database nor a NASA API key.
+	if args := flags.Args(); len(args) > 0 && args[0] == "stub-apod" {
+		runStubAPOD(args[1:])
+		return
+	}
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+	if *printConfig {
+		if err := cfg.Redacted().WriteYAML(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing configuration: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := cfg.Validate(flags.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if *printConfig {
+		return
 	}
 
-	nasaAPIKey := os.Getenv("YA_NASA_API_KEY")
-	if nasaAPIKey == "" {
-		log.Fatal("YA_NASA_API_KEY environment variable is required")
+	logOutput, err := setupLogging(cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring logging: %v\n", err)
+		os.Exit(1)
 	}
+	defer logOutput.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	postgresURL := os.Getenv("YA_POSTGRES_URL")
-	if postgresURL == "" {
-		log.Fatal("YA_POSTGRES_URL environment variable is required")
+	shutdownTracing, err := setupTracing(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Error configuring tracing: %v", err)
 	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			log.Errorf("Error exporting the remaining spans: %v", err)
+		}
+	}()
 
-	serverPort := os.Getenv("YA_SERVER_PORT")
-	if serverPort == "" {
-		log.Fatal("YA_SERVER_PORT environment variable is required")
+	client, err := httpclient.New(httpclient.Config{
+		CAFile:                cfg.HTTPClient.CAFile,
+		CertFile:              cfg.HTTPClient.CertFile,
+		KeyFile:               cfg.HTTPClient.KeyFile,
+		ProxyURL:              cfg.HTTPClient.ProxyURL,
+		Timeout:               cfg.HTTPClient.Timeout,
+		DialTimeout:           cfg.HTTPClient.DialTimeout,
+		TLSHandshakeTimeout:   cfg.HTTPClient.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.HTTPClient.ResponseHeaderTimeout,
+		MaxIdleConns:          cfg.HTTPClient.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.HTTPClient.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.HTTPClient.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.HTTPClient.IdleConnTimeout,
+		MaxResponseSize:       cfg.HTTPClient.MaxResponseSize,
+	})
+	if err != nil {
+		log.Fatalf("Error configuring HTTP client: %v", err)
 	}
 
-	db, err := sql.Open("postgres", postgresURL)
+	db, err := sql.Open("postgres", cfg.Database.URL)
 	if err != nil {
 		log.Fatalf("Error connecting to the database: %v\n", err)
 	}
 	defer db.Close()
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
 
-	imageRepo := repository.NewImageManager(db)
-	imageSvc := service.NewImageService(imageRepo)
+	pingCtx, cancelPing := context.WithTimeout(ctx, pingTimeout)
+	if err := db.PingContext(pingCtx); err != nil {
+		log.Warnf("Database is not reachable yet, the service reports itself as not ready until it is: %v", err)
+	}
+	cancelPing()
+
+	blobStore, err := newBlobStore(cfg.Storage, client)
+	if err != nil {
+		log.Fatalf("Error configuring blob store: %v", err)
+	}
+
+	imageRepo := repository.NewInstrumentedImageManager(repository.NewImageManager(db))
+	jobRunRepo := repository.NewJobRunManager(db)
+	imageSvc := service.NewInstrumentedImageService(service.NewImageService(imageRepo, blobStore))
+	jobSvc := service.NewJobService(jobRunRepo)
 	imageHandler := handler.NewImageHandler(imageSvc)
-	apodHandler := handler.NewAPODHandler(imageSvc, client)
+	metrics.RegisterStorage(imageSvc.GetStats)
+	pollPolicy := job.PollPolicy{Interval: cfg.Schedule.PollInterval, Timeout: cfg.Schedule.PollTimeout}
+	runners := make(map[string]*job.Runner, len(cfg.Sources))
+	for _, source := range cfg.Sources {
+		sourceHandler := handler.NewSourceHandler(imageSvc, newProvider(source, cfg.NASA, client), cfg.NASA.DownloadHD)
+		runners[source.Name] = job.NewRunner(sourceHandler, jobSvc, job.DefaultRetryPolicy, pollPolicy, catchUpDays)
+	}
+	adminHandler := handler.NewAdminHandler(ctx, runners, jobSvc)
+	apiKeySvc := service.NewAPIKeyService(repository.NewAPIKeyManager(db))
+	auth := handler.NewAuth(apiKeySvc, cfg.Auth.AnonymousRead)
+
+	if args := flags.Args(); len(args) > 0 {
+		switch args[0] {
+		case "backfill":
+			runBackfill(ctx, args[1:], runners)
+		case "migrate-blobs":
+			runMigrateBlobs(ctx, args[1:], imageSvc)
+		case "generate-renditions":
+			runGenerateRenditions(ctx, args[1:], imageSvc)
+		case "api-keys":
+			runAPIKeys(ctx, args[1:], apiKeySvc)
+		default:
+			flags.Usage()
+			os.Exit(2)
+		}
+		return
+	}
+
+	schedules := make(map[string]*job.Schedule, len(cfg.Sources))
+	for _, source := range cfg.Sources {
+		cron := cfg.Schedule.Cron
+		if source.Schedule != "" {
+			cron = source.Schedule
+		}
+		schedule, err := newSchedule(cfg.Schedule.Timezone, cron)
+		if err != nil {
+			log.Fatalf("Error configuring schedule of source %s: %v", source.Name, err)
+		}
+		schedules[source.Name] = schedule
+	}
+
+	healthSources := make([]handler.HealthSource, 0, len(cfg.Sources))
+	for _, source := range cfg.Sources {
+		healthSources = append(healthSources, handler.HealthSource{
+			Name:     source.Name,
+			Schedule: schedules[source.Name],
+			Daily:    source.Type == config.SourceTypeAPOD,
+		})
+	}
+	healthSvc := service.NewHealthService(repository.NewHealthChecker(db))
+	healthHandler := handler.NewHealthHandler(healthSvc, jobSvc, imageSvc, healthSources, version, cfg.Schedule.OverdueAfter)
+
+	var rateLimit *handler.RateLimit
+	if cfg.RateLimit.Enabled {
+		rateLimit = newRateLimit(cfg.RateLimit, db)
+	}
+	mux := http.NewServeMux()
+	apiRoutes := make(map[string]bool)
+	// handleAPI registers a route of the API, which requires the role and is rate limited: per IP address
+	// before authentication, so that invalid keys are throttled too, and per client afterwards.
+	handleAPI := func(pattern, role string, h http.HandlerFunc) {
+		if rateLimit == nil {
+			mux.Handle(pattern, auth.Require(role, h))
+		} else {
+			mux.Handle(pattern, rateLimit.LimitIP(auth.Require(role, rateLimit.Limit(pattern, h))))
+		}
+		apiRoutes[pattern] = true
+	}
+	handleAPI("/images", model.RoleRead, imageHandler.GetAll)
+	handleAPI("/images/date", model.RoleRead, imageHandler.GetByDate)
+	handleAPI("GET /images/search", model.RoleRead, imageHandler.Search)
+	handleAPI("GET /images/{date}/raw", model.RoleRead, imageHandler.GetRaw)
+	handleAPI("/admin/backfill", model.RoleAdmin, adminHandler.Backfill)
+	handleAPI("GET /admin/jobs", model.RoleAdmin, adminHandler.Jobs)
+	for route := range cfg.RateLimit.Routes {
+		if !apiRoutes[route] {
+			log.Fatalf("Rate limit configured for unknown route %q", route)
+		}
+	}
+	// The metrics and the status reveal the job history, so they require a key even if anonymous reads are
+	// allowed; the probes stay open to the orchestrator.
+	handleKey := func(pattern string, h http.Handler) {
+		if rateLimit == nil {
+			mux.Handle(pattern, auth.RequireKey(model.RoleRead, h))
+		} else {
+			mux.Handle(pattern, rateLimit.LimitIP(auth.RequireKey(model.RoleRead, h)))
+		}
+	}
+	handleKey("GET /metrics", metrics.Handler())
+	handleKey("GET /status", http.HandlerFunc(healthHandler.Status))
+	mux.HandleFunc("GET /healthz", healthHandler.Liveness)
+	mux.HandleFunc("GET /readyz", healthHandler.Readiness)
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           handler.RequestID(httpserver.WithRoute(mux, tracing.Handler(handler.AccessLog(metrics.Instrument(mux))))),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
 
-	http.HandleFunc("/images", imageHandler.GetAll)
-	http.HandleFunc("/images/date", imageHandler.GetByDate)
+	var runnersWG sync.WaitGroup
+	for name, runner := range runners {
+		runnersWG.Add(1)
+		go func() {
+			defer runnersWG.Done()
+			runner.Start(ctx, schedules[name], cfg.Server.ShutdownTimeout)
+		}()
+	}
 
-	done := make(chan bool)
-	go startDailyTask(nasaAPIKey, done, apodHandler)
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
 
-	if err := http.ListenAndServe(serverPort, nil); err != nil {
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Error starting server: %v\n", err)
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Error shutting down server: %v", err)
 	}
+	runnersWG.Wait()
+	adminHandler.Wait()
+	log.Info("Shutdown complete")
 }
 
-func startDailyTask(apiKey string, done chan bool, apodHandler *handler.APODHandler) {
-	ticker := time.NewTicker(15 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-done:
-			return
-		case <-ticker.C:
-			ticker = time.NewTicker(24 * time.Hour)
-
-			log.Info("Fetching APOD...")
-			apod, err := apodHandler.FetchAPOD(apiKey)
-			if err != nil {
-				log.Errorf("Error fetching APOD: %v\n", err)
-			}
-
-			log.Infof("Title: %s\nDate: %s\nExplanation: %s\nURL: %s\n", apod.Title, apod.Date, apod.Explanation, apod.URL)
-			if err := apodHandler.SaveImage(apod); err != nil {
-				log.Errorf("Error saving image: %v\n", err)
-			} else {
-				log.Infof("Image saved for date %s\n", apod.Date)
-			}
+// setupLogging configures the logger and returns its output, which the caller must close.
+func setupLogging(cfg config.LogConfig) (io.WriteCloser, error) {
+	level, err := log.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	log.SetLevel(level)
+
+	if cfg.Format == "json" {
+		log.SetFormatter(&log.JSONFormatter{})
+	}
+
+	var output io.WriteCloser = nopCloser{Writer: os.Stdout}
+	if cfg.Output == "file" {
+		output = &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
 		}
 	}
+	log.SetOutput(output)
+
+	return output, nil
+}
+
+// nopCloser keeps the standard output open when the logger or trace output is closed.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// setupTracing registers the tracer provider exporting the spans of the service and the propagator of
+// the W3C trace context in HTTP headers. It returns the function exporting the remaining spans and releasing
+// the exporter, which the caller must call on exit.
+func setupTracing(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		output   io.Closer = nopCloser{}
+		err      error
+	)
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		file, openErr := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if openErr != nil {
+			return nil, fmt.Errorf("opening trace file: %w", openErr)
+		}
+		output = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		output.Close()
+		return nil, fmt.Errorf("creating %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName), semconv.ServiceVersion(version)),
+	)
+	if err != nil {
+		output.Close()
+		return nil, fmt.Errorf("describing trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), output.Close())
+	}, nil
+}
+
+func runBackfill(ctx context.Context, args []string, runners map[string]*job.Runner) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	source := flags.String("source", model.DefaultSource, "name of the source to backfill")
+	startDate := flags.String("start", "", "first date to backfill (YYYY-MM-DD)")
+	endDate := flags.String("end", job.APODDate(time.Now()), "last date to backfill (YYYY-MM-DD)")
+	flags.Parse(args)
+
+	if _, _, err := handler.ParseDateRange(*startDate, *endDate, time.Now()); err != nil {
+		log.Fatalf("Invalid backfill range: %v", err)
+	}
+	runner, ok := runners[*source]
+	if !ok {
+		log.Fatalf("Unknown backfill source: %s", *source)
+	}
+
+	saved, err := runner.Backfill(ctx, *startDate, *endDate)
+	if err != nil {
+		log.Fatalf("Backfill finished with error after saving %d images: %v", saved, err)
+	}
+	log.Infof("Backfill of %s from %s to %s finished: %d images saved", *source, *startDate, *endDate, saved)
+}
+
+// newSchedule creates a fetch schedule from a cron expression in the time zone.
+func newSchedule(timezone, cron string) (*job.Schedule, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	return job.ParseSchedule(cron, location)
+}
+
+// newProvider creates the provider of a configured source.
+func newProvider(source config.SourceConfig, nasa config.NASAConfig, client *http.Client) provider.Provider {
+	if source.Type == config.SourceTypeDirectory {
+		return provider.NewDirectory(source.Name, source.Dir)
+	}
+	return provider.NewAPOD(source.Name, client, nasa.BaseURL, nasa.APIKey)
+}
+
+// newRateLimit creates the rate limiter of the API routes with the configured backend and limits.
+func newRateLimit(cfg config.RateLimitConfig, db *sql.DB) *handler.RateLimit {
+	limiter := ratelimit.NewMemoryLimiter()
+	if cfg.Backend == "postgres" {
+		limiter = repository.NewRateLimiter(db)
+	}
+
+	limits := make(map[string]ratelimit.Limit, len(cfg.Routes))
+	for route, limit := range cfg.Routes {
+		limits[route] = ratelimit.PerPeriod(limit.Requests, limit.Period, limit.Burst)
+	}
+	defaultLimit := ratelimit.PerPeriod(cfg.Default.Requests, cfg.Default.Period, cfg.Default.Burst)
+	ipLimit := ratelimit.PerPeriod(cfg.IP.Requests, cfg.IP.Period, cfg.IP.Burst)
+	return handler.NewRateLimit(limiter, defaultLimit, limits, ipLimit, cfg.TrustForwardedFor)
+}
+
+// newBlobStore creates the configured blob store. The S3 store sends its requests with client, so they are
+// bounded by the same timeouts and trust the same certificate authorities as the other outbound requests.
+// It returns nil for the database backend, which keeps image data in Postgres.
+func newBlobStore(cfg config.StorageConfig, client *http.Client) (storage.BlobStore, error) {
+	switch cfg.Backend {
+	case "database":
+		return nil, nil
+	case "filesystem":
+		return storage.NewFileStore(cfg.Dir), nil
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PathStyle: cfg.S3.PathStyle,
+		}, client)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+func runMigrateBlobs(ctx context.Context, args []string, imageSvc service.ImageService) {
+	flags := flag.NewFlagSet("migrate-blobs", flag.ExitOnError)
+	batchSize := flags.Int("batch", 50, "number of blobs migrated per batch")
+	flags.Parse(args)
+
+	migrated, err := imageSvc.MigrateBlobs(ctx, *batchSize)
+	if err != nil {
+		log.Fatalf("Blob migration finished with error after migrating %d blobs: %v", migrated, err)
+	}
+	log.Infof("Blob migration finished: %d blobs migrated", migrated)
+}
+
+func runGenerateRenditions(ctx context.Context, args []string, imageSvc service.ImageService) {
+	flags := flag.NewFlagSet("generate-renditions", flag.ExitOnError)
+	batchSize := flags.Int("batch", 50, "number of images processed per batch")
+	flags.Parse(args)
+
+	generated, err := imageSvc.GenerateRenditions(ctx, *batchSize)
+	if err != nil {
+		log.Fatalf("Rendition generation finished with error after processing %d images: %v", generated, err)
+	}
+	log.Infof("Rendition generation finished: renditions generated for %d images", generated)
+}
+
+func runAPIKeys(ctx context.Context, args []string, apiKeySvc service.APIKeyService) {
+	if len(args) == 0 {
+		log.Fatal("Usage: api-keys create|list|revoke [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("api-keys create", flag.ExitOnError)
+		name := flags.String("name", "", "name describing who uses the key")
+		role := flags.String("role", model.RoleRead, "role of the key: read or admin")
+		flags.Parse(args[1:])
+
+		key, secret, err := apiKeySvc.Create(ctx, *name, *role)
+		if err != nil {
+			log.Fatalf("Error creating API key: %v", err)
+		}
+		log.Infof("API key %s (%s) created with the %s role; store it now, it cannot be shown again", key.ID, key.Name, key.Role)
+		fmt.Println(secret)
+	case "list":
+		keys, err := apiKeySvc.List(ctx)
+		if err != nil {
+			log.Fatalf("Error listing API keys: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tROLE\tCREATED\tLAST USED\tREVOKED")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix, key.Role,
+				key.CreatedAt.Format(time.RFC3339), formatOptionalTime(key.LastUsedAt), formatOptionalTime(key.RevokedAt))
+		}
+		w.Flush()
+	case "revoke":
+		flags := flag.NewFlagSet("api-keys revoke", flag.ExitOnError)
+		rawID := flags.String("id", "", "ID of the key to revoke")
+		flags.Parse(args[1:])
+
+		id, err := uuid.Parse(*rawID)
+		if err != nil {
+			log.Fatalf("Invalid API key ID %q: %v", *rawID, err)
+		}
+		if err := apiKeySvc.Revoke(ctx, id); err != nil {
+			log.Fatalf("Error revoking API key: %v", err)
+		}
+		log.Infof("API key %s revoked", id)
+	default:
+		log.Fatalf("Unknown api-keys command: %s", args[0])
+	}
+}
+
+// formatOptionalTime formats t for the API key listing, or returns "-" if it is nil.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func runStubAPOD(args []string) {
+	flags := flag.NewFlagSet("stub-apod", flag.ExitOnError)
+	addr := flags.String("addr", ":8081", "address the stub server listens on")
+	fixtures := flags.String("fixtures", "internal/apodstub/testdata", "directory of recorded APOD entries and images")
+	rateLimit := flags.Int("rate-limit", 0, "requests allowed per rate window (0 disables rate limiting)")
+	rateWindow := flags.Duration("rate-window", time.Hour, "window of the rate limit")
+	failEvery := flags.Int("fail-every", 0, "fail every n-th request (0 disables failures)")
+	failStatus := flags.Int("fail-status", http.StatusServiceUnavailable, "status code of failed requests")
+	latency := flags.Duration("latency", 0, "delay of every response")
+	flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr: *addr,
+		Handler: apodstub.NewServer(*fixtures, apodstub.Options{
+			RateLimit:  *rateLimit,
+			RateWindow: *rateWindow,
+			FailEvery:  *failEvery,
+			FailStatus: *failStatus,
+			Latency:    *latency,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving APOD fixtures from %s at http://%s%s\n", *fixtures, *addr, apodstub.APIPath)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error running stub server: %v\n", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS image_renditions (
+    image_id UUID NOT NULL REFERENCES images (id) ON DELETE CASCADE,
+    name VARCHAR(20) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size BIGINT NOT NULL,
+    data BYTEA,
+    blob_key TEXT,
+    PRIMARY KEY (image_id, name)
+);
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS images_source_width_height_idx;
+
+ALTER TABLE images
+    DROP COLUMN IF EXISTS format,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS checksum,
+    DROP COLUMN IF EXISTS dominant_color,
+    DROP COLUMN IF EXISTS camera_make,
+    DROP COLUMN IF EXISTS camera_model,
+    DROP COLUMN IF EXISTS exposure_time,
+    DROP COLUMN IF EXISTS f_number,
+    DROP COLUMN IF EXISTS iso,
+    DROP COLUMN IF EXISTS focal_length,
+    DROP COLUMN IF EXISTS captured_at;
@@ -0,0 +1,15 @@
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS format VARCHAR(10),
+    ADD COLUMN IF NOT EXISTS width INTEGER,
+    ADD COLUMN IF NOT EXISTS height INTEGER,
+    ADD COLUMN IF NOT EXISTS checksum CHAR(64),
+    ADD COLUMN IF NOT EXISTS dominant_color CHAR(7),
+    ADD COLUMN IF NOT EXISTS camera_make TEXT,
+    ADD COLUMN IF NOT EXISTS camera_model TEXT,
+    ADD COLUMN IF NOT EXISTS exposure_time VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS f_number DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS iso INTEGER,
+    ADD COLUMN IF NOT EXISTS focal_length DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS captured_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS images_source_width_height_idx ON images (source, width, height);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS images_checksum_idx;
+
+ALTER TABLE images
+    DROP CONSTRAINT IF EXISTS images_checksum_fkey,
+    DROP CONSTRAINT IF EXISTS images_hd_checksum_fkey;
+
+UPDATE images SET data = blobs.data, blob_key = blobs.blob_key FROM blobs WHERE blobs.checksum = images.checksum;
+UPDATE images SET hd_data = blobs.data, hd_blob_key = blobs.blob_key FROM blobs WHERE blobs.checksum = images.hd_checksum;
+
+ALTER TABLE images DROP COLUMN IF EXISTS hd_checksum;
+DROP TABLE IF EXISTS blobs;
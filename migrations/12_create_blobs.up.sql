@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS blobs (
+    checksum CHAR(64) PRIMARY KEY,
+    data BYTEA,
+    blob_key TEXT,
+    size BIGINT NOT NULL
+);
+
+ALTER TABLE images ADD COLUMN IF NOT EXISTS hd_checksum CHAR(64);
+
+-- Data kept in the database is hashed and moved into blobs, one row per distinct picture.
+UPDATE images SET checksum = encode(sha256(data), 'hex') WHERE octet_length(data) > 0;
+UPDATE images SET hd_checksum = encode(sha256(hd_data), 'hex') WHERE octet_length(hd_data) > 0;
+
+INSERT INTO blobs (checksum, data, size)
+SELECT DISTINCT ON (checksum) checksum, data, octet_length(data) FROM (
+    SELECT checksum, data FROM images WHERE octet_length(data) > 0
+    UNION ALL
+    SELECT hd_checksum, hd_data FROM images WHERE octet_length(hd_data) > 0
+) AS stored
+ORDER BY checksum
+ON CONFLICT (checksum) DO NOTHING;
+
+-- Data in the blob store whose checksum is known is referenced by the key of one of its copies.
+INSERT INTO blobs (checksum, blob_key, size)
+SELECT DISTINCT ON (checksum) checksum, blob_key, COALESCE(size, 0) FROM images
+WHERE checksum IS NOT NULL AND blob_key IS NOT NULL
+ORDER BY checksum, date
+ON CONFLICT (checksum) DO NOTHING;
+
+UPDATE images SET size = COALESCE(size, octet_length(data)), hd_size = COALESCE(hd_size, octet_length(hd_data));
+UPDATE images SET checksum = NULL WHERE checksum NOT IN (SELECT checksum FROM blobs);
+UPDATE images SET data = NULL, blob_key = NULL WHERE checksum IS NOT NULL;
+UPDATE images SET hd_data = NULL, hd_blob_key = NULL WHERE hd_checksum IS NOT NULL;
+
+ALTER TABLE images
+    ADD CONSTRAINT images_checksum_fkey FOREIGN KEY (checksum) REFERENCES blobs (checksum) DEFERRABLE INITIALLY DEFERRED,
+    ADD CONSTRAINT images_hd_checksum_fkey FOREIGN KEY (hd_checksum) REFERENCES blobs (checksum) DEFERRABLE INITIALLY DEFERRED;
+
+CREATE INDEX IF NOT EXISTS images_checksum_idx ON images (checksum);
@@ -0,0 +1,2 @@
+-- The redacted API keys cannot be restored.
+SELECT 1;
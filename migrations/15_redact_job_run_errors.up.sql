@@ -0,0 +1,4 @@
+-- Errors recorded before job runs stored only the kind and message of domain errors may include
+-- the URL of a failed NASA API request along with its API key.
+UPDATE job_runs SET error = regexp_replace(error, 'api_key=[^&"\s]*', 'api_key=REDACTED', 'g')
+WHERE error LIKE '%api_key=%';
@@ -0,0 +1,6 @@
+DELETE FROM images WHERE data IS NULL;
+ALTER TABLE images
+    DROP COLUMN IF EXISTS url,
+    DROP COLUMN IF EXISTS video_provider,
+    DROP COLUMN IF EXISTS thumbnail_url,
+    ALTER COLUMN data SET NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS url TEXT,
+    ADD COLUMN IF NOT EXISTS video_provider VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS thumbnail_url TEXT,
+    ALTER COLUMN data DROP NOT NULL;
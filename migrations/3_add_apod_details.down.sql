@@ -0,0 +1,5 @@
+ALTER TABLE images
+    DROP COLUMN IF EXISTS hd_url,
+    DROP COLUMN IF EXISTS copyright,
+    DROP COLUMN IF EXISTS service_version,
+    DROP COLUMN IF EXISTS hd_data;
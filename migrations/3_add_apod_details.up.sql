@@ -0,0 +1,5 @@
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS hd_url TEXT,
+    ADD COLUMN IF NOT EXISTS copyright TEXT,
+    ADD COLUMN IF NOT EXISTS service_version VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS hd_data BYTEA;
@@ -0,0 +1,5 @@
+ALTER TABLE images
+    DROP COLUMN IF EXISTS blob_key,
+    DROP COLUMN IF EXISTS hd_blob_key,
+    DROP COLUMN IF EXISTS size,
+    DROP COLUMN IF EXISTS hd_size;
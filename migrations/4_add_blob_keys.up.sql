@@ -0,0 +1,7 @@
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS blob_key TEXT,
+    ADD COLUMN IF NOT EXISTS hd_blob_key TEXT,
+    ADD COLUMN IF NOT EXISTS size BIGINT,
+    ADD COLUMN IF NOT EXISTS hd_size BIGINT;
+
+UPDATE images SET size = octet_length(data), hd_size = octet_length(hd_data);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS job_runs_job_apod_date_idx;
+
+ALTER TABLE job_runs DROP COLUMN IF EXISTS apod_date;
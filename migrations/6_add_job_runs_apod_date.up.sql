@@ -0,0 +1,3 @@
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS apod_date TEXT;
+
+CREATE INDEX IF NOT EXISTS job_runs_job_apod_date_idx ON job_runs (job, apod_date) WHERE status = 'success';
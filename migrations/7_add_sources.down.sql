@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS job_runs_job_source_apod_date_idx;
+CREATE INDEX IF NOT EXISTS job_runs_job_apod_date_idx ON job_runs (job, apod_date) WHERE status = 'success';
+ALTER TABLE job_runs DROP COLUMN IF EXISTS source;
+
+DELETE FROM images WHERE source <> 'apod';
+ALTER TABLE images DROP CONSTRAINT IF EXISTS images_source_date_key;
+ALTER TABLE images ADD CONSTRAINT images_date_key UNIQUE (date);
+ALTER TABLE images DROP COLUMN IF EXISTS source;
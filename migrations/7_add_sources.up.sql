@@ -0,0 +1,7 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'apod';
+ALTER TABLE images DROP CONSTRAINT IF EXISTS images_date_key;
+ALTER TABLE images ADD CONSTRAINT images_source_date_key UNIQUE (source, date);
+
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'apod';
+DROP INDEX IF EXISTS job_runs_job_apod_date_idx;
+CREATE INDEX IF NOT EXISTS job_runs_job_source_apod_date_idx ON job_runs (job, source, apod_date) WHERE status = 'success';
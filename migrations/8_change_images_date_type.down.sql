@@ -0,0 +1 @@
+ALTER TABLE images ALTER COLUMN date TYPE TEXT USING to_char(date, 'YYYY-MM-DD');
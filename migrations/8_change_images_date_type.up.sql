@@ -0,0 +1 @@
+ALTER TABLE images ALTER COLUMN date TYPE DATE USING date::date;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS images_search_vector_idx;
+ALTER TABLE images DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,5 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(explanation, '')), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS images_search_vector_idx ON images USING GIN (search_vector);